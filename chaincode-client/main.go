@@ -1,11 +1,17 @@
 package main
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 )
@@ -27,16 +33,62 @@ type Config struct {
 	Peer2Address     string
 	Peer2Port        string
 	Peer2TLSCertFile string
+	// InvokeAttempts is how many times an invoke is tried when waiting for
+	// the commit event times out
+	InvokeAttempts int
 }
 
 // Asset represents the chaincode asset structure
 type Asset struct {
-	ID        string `json:"ID"`
-	Owner     string `json:"Owner"`
-	Value     int64  `json:"Value"`
-	CreatedAt string `json:"CreatedAt"`
-	UpdatedAt string `json:"UpdatedAt"`
-	Version   int    `json:"Version"`
+	ID        string `json:"id"`
+	Owner     string `json:"owner"`
+	Value     Money  `json:"value"`
+	CreatedAt string `json:"createdAt"`
+	UpdatedAt string `json:"updatedAt"`
+	Version   int    `json:"version"`
+
+	Tags            []string `json:"tags,omitempty"`
+	ValueCommitment string   `json:"valueCommitment,omitempty"`
+	ValueSalt       string   `json:"valueSalt,omitempty"`
+}
+
+// Money represents a monetary value as stored by the chaincode: an amount in
+// minor units of an ISO-4217 currency
+type Money struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+	Decimals int    `json:"decimals"`
+}
+
+// String formats the value like "125.50 EUR"
+func (m Money) String() string {
+	amount := strconv.FormatInt(m.Amount, 10)
+	sign := ""
+	if m.Amount < 0 {
+		sign, amount = "-", amount[1:]
+	}
+	if m.Decimals > 0 {
+		if len(amount) <= m.Decimals {
+			amount = strings.Repeat("0", m.Decimals-len(amount)+1) + amount
+		}
+		amount = amount[:len(amount)-m.Decimals] + "." + amount[len(amount)-m.Decimals:]
+	}
+	return sign + amount + " " + m.Currency
+}
+
+// moneyPattern matches values like "125.50 EUR"
+var moneyPattern = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s+([A-Za-z]{3})$`)
+
+// parseMoney checks that a value given as "125.50 EUR" (one or two arguments)
+// is well formed and normalizes it. Supported currencies and decimal places
+// are validated by the chaincode.
+func parseMoney(args ...string) (string, error) {
+	value := strings.Join(args, " ")
+	m := moneyPattern.FindStringSubmatch(strings.TrimSpace(value))
+	if m == nil {
+		return "", fmt.Errorf("invalid value %q, expected an amount and currency like \"125.50 EUR\"", value)
+	}
+	return m[1] + " " + strings.ToUpper(m[2]), nil
 }
 
 var config Config
@@ -60,6 +112,7 @@ func init() {
 		Peer2Address:     "peer0.org2.example.com",
 		Peer2Port:        "9051",
 		Peer2TLSCertFile: filepath.Join(homeDir, "organizations", "peerOrganizations", "org2.example.com", "peers", "peer0.org2.example.com", "tls", "ca.crt"),
+		InvokeAttempts:   3,
 	}
 }
 
@@ -91,16 +144,41 @@ func runPeerCommand(args ...string) (string, error) {
 	return string(output), nil
 }
 
-// invokeChaincode executes a chaincode invoke operation (write)
+// invokeChaincode executes a chaincode invoke operation (write).
+// Each call gets a request ID that is sent as transient data and reused on
+// every retry, so the chaincode replays the original result instead of
+// applying the operation twice when an earlier attempt already committed.
 func invokeChaincode(function string, args ...string) (string, error) {
+	return invokeChaincodeWithFlags(nil, function, args...)
+}
+
+// invokeChaincodeWithFlags is invokeChaincode with extra peer CLI flags, e.g. --isInit
+func invokeChaincodeWithFlags(flags []string, function string, args ...string) (string, error) {
+	return invokeChaincodeWith(flags, nil, function, args...)
+}
+
+// invokeChaincodeWithTransient is invokeChaincode with extra transient data,
+// which reaches the chaincode but is not recorded in the block
+func invokeChaincodeWithTransient(transient map[string]string, function string, args ...string) (string, error) {
+	return invokeChaincodeWith(nil, transient, function, args...)
+}
+
+// invokeChaincodeWith executes an invoke with optional peer CLI flags and transient data
+func invokeChaincodeWith(flags []string, transient map[string]string, function string, args ...string) (string, error) {
 	// Build the JSON args array
 	argsJSON := buildArgsJSON(function, args...)
 
-	// Execute peer chaincode invoke with multi-peer endorsement (Org1 + Org2)
-	fmt.Printf("Requesting endorsement from Org1 (%s:%s) and Org2 (%s:%s)\n",
-		config.PeerAddress, config.PeerPort, config.Peer2Address, config.Peer2Port)
+	requestID, err := newRequestID()
+	if err != nil {
+		return "", err
+	}
+	values := map[string]string{"requestId": requestID}
+	for k, v := range transient {
+		values[k] = v
+	}
+	transientJSON := buildTransientJSON(values)
 
-	output, err := runPeerCommand(
+	peerArgs := []string{
 		"chaincode", "invoke",
 		"-o", config.OrdererAddress,
 		"--tls",
@@ -108,14 +186,42 @@ func invokeChaincode(function string, args ...string) (string, error) {
 		"-C", config.ChannelName,
 		"-n", config.ChaincodeName,
 		"-c", argsJSON,
+		"--transient", transientJSON,
 		"--peerAddresses", fmt.Sprintf("%s:%s", config.PeerAddress, config.PeerPort),
 		"--tlsRootCertFiles", config.TLSCertFile,
 		"--peerAddresses", fmt.Sprintf("%s:%s", config.Peer2Address, config.Peer2Port),
 		"--tlsRootCertFiles", config.Peer2TLSCertFile,
 		"--waitForEvent",
-	)
+	}
+	peerArgs = append(peerArgs, flags...)
 
-	return output, err
+	var output string
+	for attempt := 1; ; attempt++ {
+		// Execute peer chaincode invoke with multi-peer endorsement (Org1 + Org2)
+		fmt.Printf("Requesting endorsement from Org1 (%s:%s) and Org2 (%s:%s)\n",
+			config.PeerAddress, config.PeerPort, config.Peer2Address, config.Peer2Port)
+
+		output, err = runPeerCommand(peerArgs...)
+		if err == nil || attempt >= config.InvokeAttempts || !isTimeout(output) {
+			return output, err
+		}
+		fmt.Printf("Timed out waiting for commit, retrying (attempt %d/%d, request ID %s)\n",
+			attempt+1, config.InvokeAttempts, requestID)
+	}
+}
+
+// newRequestID returns a random ID identifying one logical invoke across retries
+func newRequestID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate request ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// isTimeout reports whether peer output indicates the transaction outcome is unknown
+func isTimeout(output string) bool {
+	return strings.Contains(output, "timed out") || strings.Contains(output, "deadline exceeded")
 }
 
 // queryChaincode executes a chaincode query operation (read)
@@ -144,11 +250,21 @@ func buildArgsJSON(function string, args ...string) string {
 	return string(argsBytes)
 }
 
+// buildTransientJSON builds the --transient argument, which expects base64 encoded values
+func buildTransientJSON(values map[string]string) string {
+	transient := make(map[string]string, len(values))
+	for k, v := range values {
+		transient[k] = base64.StdEncoding.EncodeToString([]byte(v))
+	}
+	transientBytes, _ := json.Marshal(transient)
+	return string(transientBytes)
+}
+
 // CreateAsset creates a new asset
-func CreateAsset(id, owner string, value int64) error {
-	fmt.Printf("Creating asset: ID=%s, Owner=%s, Value=%d\n", id, owner, value)
+func CreateAsset(id, owner, value string) error {
+	fmt.Printf("Creating asset: ID=%s, Owner=%s, Value=%s\n", id, owner, value)
 
-	output, err := invokeChaincode("CreateAsset", id, owner, strconv.FormatInt(value, 10))
+	output, err := invokeChaincode("CreateAsset", id, owner, value)
 	if err != nil {
 		return fmt.Errorf("failed to create asset: %w\nOutput: %s", err, output)
 	}
@@ -157,6 +273,40 @@ func CreateAsset(id, owner string, value int64) error {
 	return nil
 }
 
+// CreateAssetAuto creates a new asset with a chaincode-assigned ID and returns that ID
+func CreateAssetAuto(owner, value string) (string, error) {
+	fmt.Printf("Creating asset with generated ID: Owner=%s, Value=%s\n", owner, value)
+
+	output, err := invokeChaincode("CreateAssetAuto", owner, value)
+	if err != nil {
+		return "", fmt.Errorf("failed to create asset: %w\nOutput: %s", err, output)
+	}
+
+	id, err := parsePayload(output)
+	if err != nil {
+		return "", fmt.Errorf("failed to read assigned ID: %w\nOutput: %s", err, output)
+	}
+
+	fmt.Printf("Asset created successfully:\n%s\n", output)
+	return id, nil
+}
+
+// payloadPattern matches the quoted payload printed by `peer chaincode invoke`
+var payloadPattern = regexp.MustCompile(`payload:("(?:[^"\\]|\\.)*")`)
+
+// parsePayload extracts the transaction result from peer invoke output
+func parsePayload(output string) (string, error) {
+	m := payloadPattern.FindStringSubmatch(output)
+	if m == nil {
+		return "", fmt.Errorf("no payload in peer output")
+	}
+	payload, err := strconv.Unquote(m[1])
+	if err != nil {
+		return "", fmt.Errorf("invalid payload %s: %w", m[1], err)
+	}
+	return payload, nil
+}
+
 // ReadAsset reads an asset by ID
 func ReadAsset(id string) (*Asset, error) {
 	fmt.Printf("Reading asset: ID=%s\n", id)
@@ -176,6 +326,22 @@ func ReadAsset(id string) (*Asset, error) {
 	return &asset, nil
 }
 
+// ReadAssetAsOf reads an asset as it was at an RFC 3339 timestamp
+func ReadAssetAsOf(id, timestamp string) (*Asset, error) {
+	fmt.Printf("Reading asset: ID=%s, AsOf=%s\n", id, timestamp)
+
+	output, err := queryChaincode("ReadAssetAsOf", id, timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read asset: %w\nOutput: %s", err, output)
+	}
+
+	var asset Asset
+	if err := json.Unmarshal([]byte(strings.TrimSpace(output)), &asset); err != nil {
+		return nil, fmt.Errorf("failed to parse asset JSON: %w\nOutput: %s", err, output)
+	}
+	return &asset, nil
+}
+
 // UpdateAssetOwner updates the owner of an asset
 func UpdateAssetOwner(id, newOwner string) error {
 	fmt.Printf("Updating asset owner: ID=%s, NewOwner=%s\n", id, newOwner)
@@ -190,10 +356,10 @@ func UpdateAssetOwner(id, newOwner string) error {
 }
 
 // UpdateAssetValue updates the value of an asset
-func UpdateAssetValue(id string, newValue int64) error {
-	fmt.Printf("Updating asset value: ID=%s, NewValue=%d\n", id, newValue)
+func UpdateAssetValue(id, newValue string) error {
+	fmt.Printf("Updating asset value: ID=%s, NewValue=%s\n", id, newValue)
 
-	output, err := invokeChaincode("UpdateAssetValue", id, strconv.FormatInt(newValue, 10))
+	output, err := invokeChaincode("UpdateAssetValue", id, newValue)
 	if err != nil {
 		return fmt.Errorf("failed to update asset value: %w\nOutput: %s", err, output)
 	}
@@ -250,11 +416,115 @@ func GetAllAssets() ([]*Asset, error) {
 	return assets, nil
 }
 
+// SeedLedger loads the chaincode's embedded seed dataset. With isInit the
+// invocation is sent as the chaincode's --isInit call, as required when the
+// chaincode definition was committed with --init-required.
+func SeedLedger(isInit bool) error {
+	fmt.Println("Seeding ledger with the embedded dataset...")
+
+	var flags []string
+	if isInit {
+		flags = append(flags, "--isInit")
+	}
+	output, err := invokeChaincodeWithFlags(flags, "InitLedger")
+	if err != nil {
+		return fmt.Errorf("failed to seed ledger: %w\nOutput: %s", err, output)
+	}
+
+	fmt.Printf("Ledger seeded successfully:\n%s\n", output)
+	return nil
+}
+
+// RegisterOwner registers an owner profile, which starts with a pending KYC status.
+// The name is sent as transient data so that it stays in the org's private data collection.
+func RegisterOwner(owner, name, jurisdiction string) error {
+	fmt.Printf("Registering owner: Owner=%s, Name=%s, Jurisdiction=%s\n", owner, name, jurisdiction)
+
+	personal, err := json.Marshal(map[string]string{"name": name})
+	if err != nil {
+		return fmt.Errorf("failed to encode personal data: %w", err)
+	}
+	output, err := invokeChaincodeWithTransient(map[string]string{"personal": string(personal)},
+		"OwnerRegistryContract:RegisterOwner", owner, "", jurisdiction)
+	if err != nil {
+		return fmt.Errorf("failed to register owner: %w\nOutput: %s", err, output)
+	}
+
+	fmt.Printf("Owner registered successfully:\n%s\n", output)
+	return nil
+}
+
+// SetKYCStatus records a KYC review outcome; only compliance identities may do this
+func SetKYCStatus(owner, status, note string) error {
+	fmt.Printf("Setting KYC status: Owner=%s, Status=%s\n", owner, status)
+
+	output, err := invokeChaincode("OwnerRegistryContract:SetKYCStatus", owner, status, note)
+	if err != nil {
+		return fmt.Errorf("failed to set KYC status: %w\nOutput: %s", err, output)
+	}
+
+	fmt.Printf("KYC status set successfully:\n%s\n", output)
+	return nil
+}
+
+// ErasePersonalData purges an owner's personal data and leaves a tombstone profile;
+// only compliance org admins may do this
+func ErasePersonalData(owner, reason string) error {
+	fmt.Printf("Erasing personal data: Owner=%s, Reason=%s\n", owner, reason)
+
+	output, err := invokeChaincode("OwnerRegistryContract:ErasePersonalData", owner, reason)
+	if err != nil {
+		return fmt.Errorf("failed to erase personal data: %w\nOutput: %s", err, output)
+	}
+
+	fmt.Printf("Personal data erased successfully:\n%s\n", output)
+	return nil
+}
+
+// AnchorDocument hashes a local file and anchors its SHA-256 digest to an asset
+func AnchorDocument(id, path, docType, uri string) (string, error) {
+	digest, err := hashFile(path)
+	if err != nil {
+		return "", err
+	}
+	fmt.Printf("Anchoring document: ID=%s, File=%s, DocType=%s, SHA256=%s\n", id, path, docType, digest)
+
+	output, err := invokeChaincode("AnchorDocument", id, docType, digest, uri)
+	if err != nil {
+		return "", fmt.Errorf("failed to anchor document: %w\nOutput: %s", err, output)
+	}
+
+	fmt.Printf("Document anchored successfully:\n%s\n", output)
+	return digest, nil
+}
+
+// hashFile returns the hex encoded SHA-256 digest of a file
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open document: %w", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to hash document: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
 // printAsset prints an asset in a formatted way
 func printAsset(asset *Asset) {
 	fmt.Printf("  ID: %s\n", asset.ID)
 	fmt.Printf("  Owner: %s\n", asset.Owner)
-	fmt.Printf("  Value: %d\n", asset.Value)
+	if asset.ValueCommitment != "" && asset.ValueSalt == "" {
+		fmt.Printf("  Value: concealed (commitment %s)\n", asset.ValueCommitment)
+	} else {
+		fmt.Printf("  Value: %s\n", asset.Value)
+	}
+	if len(asset.Tags) > 0 {
+		fmt.Printf("  Tags: %s\n", strings.Join(asset.Tags, ", "))
+	}
 	fmt.Printf("  CreatedAt: %s\n", asset.CreatedAt)
 	fmt.Printf("  UpdatedAt: %s\n", asset.UpdatedAt)
 	fmt.Printf("  Version: %d\n", asset.Version)
@@ -265,13 +535,19 @@ func main() {
 	if len(os.Args) < 2 {
 		fmt.Println("Usage: ./chaincode-client <command> [args...]")
 		fmt.Println("\nCommands:")
-		fmt.Println("  create <id> <owner> <value>    - Create a new asset")
-		fmt.Println("  read <id>                       - Read an asset by ID")
+		fmt.Println("  create <id> <owner> <value>    - Create a new asset (value like \"125.50 EUR\")")
+		fmt.Println("  create --auto <owner> <value>  - Create a new asset with a generated ID")
+		fmt.Println("  read <id> [--as-of <time>]     - Read an asset, optionally as it was at an RFC 3339 time")
 		fmt.Println("  update-owner <id> <newOwner>   - Update asset owner")
 		fmt.Println("  update-value <id> <newValue>   - Update asset value")
 		fmt.Println("  delete <id>                    - Delete an asset")
 		fmt.Println("  exists <id>                    - Check if asset exists")
 		fmt.Println("  list                           - List all assets")
+		fmt.Println("  anchor <id> <file> [docType] [uri] - Anchor a document's SHA-256 to an asset")
+		fmt.Println("  seed [--init]                  - Load the chaincode's seed dataset")
+		fmt.Println("  register-owner <owner> <name> [jurisdiction] - Register an owner for KYC review")
+		fmt.Println("  set-kyc <owner> <status> [note] - Set an owner's KYC status (compliance org only)")
+		fmt.Println("  erase-owner <owner> <reason>   - Purge an owner's personal data (compliance org admin only)")
 		os.Exit(1)
 	}
 
@@ -279,15 +555,31 @@ func main() {
 
 	switch command {
 	case "create":
-		if len(os.Args) != 5 {
+		if len(os.Args) >= 5 && len(os.Args) <= 6 && os.Args[2] == "--auto" {
+			owner := os.Args[3]
+			value, err := parseMoney(os.Args[4:]...)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			id, err := CreateAssetAuto(owner, value)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("\nAssigned asset ID: %s\n", id)
+			break
+		}
+		if len(os.Args) < 5 || len(os.Args) > 6 {
 			fmt.Println("Usage: ./chaincode-client create <id> <owner> <value>")
+			fmt.Println("       ./chaincode-client create --auto <owner> <value>")
 			os.Exit(1)
 		}
 		id := os.Args[2]
 		owner := os.Args[3]
-		value, err := strconv.ParseInt(os.Args[4], 10, 64)
+		value, err := parseMoney(os.Args[4:]...)
 		if err != nil {
-			fmt.Printf("Invalid value: %s\n", os.Args[4])
+			fmt.Printf("Error: %v\n", err)
 			os.Exit(1)
 		}
 		if err := CreateAsset(id, owner, value); err != nil {
@@ -296,12 +588,18 @@ func main() {
 		}
 
 	case "read":
-		if len(os.Args) != 3 {
-			fmt.Println("Usage: ./chaincode-client read <id>")
+		if len(os.Args) != 3 && (len(os.Args) != 5 || os.Args[3] != "--as-of") {
+			fmt.Println("Usage: ./chaincode-client read <id> [--as-of <time>]")
 			os.Exit(1)
 		}
 		id := os.Args[2]
-		asset, err := ReadAsset(id)
+		var asset *Asset
+		var err error
+		if len(os.Args) == 5 {
+			asset, err = ReadAssetAsOf(id, os.Args[4])
+		} else {
+			asset, err = ReadAsset(id)
+		}
 		if err != nil {
 			fmt.Printf("Error: %v\n", err)
 			os.Exit(1)
@@ -324,14 +622,14 @@ func main() {
 		}
 
 	case "update-value":
-		if len(os.Args) != 4 {
+		if len(os.Args) < 4 || len(os.Args) > 5 {
 			fmt.Println("Usage: ./chaincode-client update-value <id> <newValue>")
 			os.Exit(1)
 		}
 		id := os.Args[2]
-		newValue, err := strconv.ParseInt(os.Args[3], 10, 64)
+		newValue, err := parseMoney(os.Args[3:]...)
 		if err != nil {
-			fmt.Printf("Invalid value: %s\n", os.Args[3])
+			fmt.Printf("Error: %v\n", err)
 			os.Exit(1)
 		}
 		if err := UpdateAssetValue(id, newValue); err != nil {
@@ -375,6 +673,76 @@ func main() {
 			fmt.Printf("\nFound %d assets:\n", len(assets))
 		}
 
+	case "anchor":
+		if len(os.Args) < 4 || len(os.Args) > 6 {
+			fmt.Println("Usage: ./chaincode-client anchor <id> <file> [docType] [uri]")
+			os.Exit(1)
+		}
+		id := os.Args[2]
+		path := os.Args[3]
+		docType := "document"
+		if len(os.Args) > 4 {
+			docType = os.Args[4]
+		}
+		uri := ""
+		if len(os.Args) > 5 {
+			uri = os.Args[5]
+		}
+		digest, err := AnchorDocument(id, path, docType, uri)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("\nAnchored %s to asset %s\n", digest, id)
+
+	case "seed":
+		if len(os.Args) > 3 || (len(os.Args) == 3 && os.Args[2] != "--init") {
+			fmt.Println("Usage: ./chaincode-client seed [--init]")
+			os.Exit(1)
+		}
+		if err := SeedLedger(len(os.Args) == 3); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "register-owner":
+		if len(os.Args) < 4 || len(os.Args) > 5 {
+			fmt.Println("Usage: ./chaincode-client register-owner <owner> <name> [jurisdiction]")
+			os.Exit(1)
+		}
+		jurisdiction := ""
+		if len(os.Args) == 5 {
+			jurisdiction = os.Args[4]
+		}
+		if err := RegisterOwner(os.Args[2], os.Args[3], jurisdiction); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "set-kyc":
+		if len(os.Args) < 4 || len(os.Args) > 5 {
+			fmt.Println("Usage: ./chaincode-client set-kyc <owner> <pending|verified|rejected|suspended> [note]")
+			os.Exit(1)
+		}
+		note := ""
+		if len(os.Args) == 5 {
+			note = os.Args[4]
+		}
+		if err := SetKYCStatus(os.Args[2], os.Args[3], note); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "erase-owner":
+		if len(os.Args) != 4 {
+			fmt.Println("Usage: ./chaincode-client erase-owner <owner> <reason>")
+			os.Exit(1)
+		}
+		if err := ErasePersonalData(os.Args[2], os.Args[3]); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
 	default:
 		fmt.Printf("Unknown command: %s\n", command)
 		fmt.Println("Use 'help' to see available commands")
@@ -2,68 +2,175 @@ package main
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
-	"strings"
 	"time"
 
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
 )
 
+const (
+	defaultAutoIDPrefix = "asset"
+	// autoIDLength is the number of hex characters of the tx ID kept in
+	// generated IDs (128 bits).
+	autoIDLength = 32
+)
+
 type AssetContract struct {
 	contractapi.Contract
+
+	// AutoIDPrefix is prepended to IDs generated by CreateAssetAuto.
+	AutoIDPrefix string
+	// RegulatorMSP is the MSP whose identities may freeze assets.
+	RegulatorMSP string
+	// ArbitratorMSP is the MSP whose identities may resolve disputes.
+	ArbitratorMSP string
+	// AppraiserMSPs are the MSPs whose identities may submit valuations.
+	AppraiserMSPs []string
+	// ComplianceMSP is the MSP whose admins may seed the ledger, which
+	// registers the seed owners as KYC verified. It must match
+	// OwnerRegistryContract.ComplianceMSP.
+	ComplianceMSP string
+	// GovernanceMSP is the MSP whose admins may change the contract
+	// configuration.
+	GovernanceMSP string
 }
 
-func (c *AssetContract) CreateAsset(ctx contractapi.TransactionContextInterface, id string, owner string, value int64) error {
-	id = strings.TrimSpace(id)
-	owner = strings.TrimSpace(owner)
+func (c *AssetContract) CreateAsset(ctx contractapi.TransactionContextInterface, id string, owner string, value string) error {
+	guard, err := beginRequest(ctx, "CreateAsset", id, owner, value)
+	if err != nil {
+		return err
+	}
+	if _, ok := guard.replayed(); ok {
+		return nil
+	}
 
-	if id == "" {
-		return errors.New("id is required")
+	cfg, err := readConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if id, err = cfg.normalizeID("id", id); err != nil {
+		return err
 	}
-	if owner == "" {
-		return errors.New("owner is required")
+	if owner, err = cfg.normalizeOwner("owner", owner); err != nil {
+		return err
 	}
-	if value < 0 {
-		return errors.New("value must be >= 0")
+	if err := requireVerifiedOwner(ctx, owner); err != nil {
+		return err
 	}
+	if err := c.createAsset(ctx, "CreateAsset", id, owner, value); err != nil {
+		return err
+	}
+	return guard.complete(ctx, id)
+}
 
-	exists, err := c.AssetExists(ctx, id)
+func (c *AssetContract) CreateAssetAuto(ctx contractapi.TransactionContextInterface, owner string, value string) (string, error) {
+	guard, err := beginRequest(ctx, "CreateAssetAuto", owner, value)
+	if err != nil {
+		return "", err
+	}
+	if result, ok := guard.replayed(); ok {
+		return result, nil
+	}
+
+	owner, err = validOwner(ctx, "owner", owner)
+	if err != nil {
+		return "", err
+	}
+	if err := requireVerifiedOwner(ctx, owner); err != nil {
+		return "", err
+	}
+	id := c.autoAssetID(ctx.GetStub().GetTxID())
+	if err := c.createAsset(ctx, "CreateAssetAuto", id, owner, value); err != nil {
+		return "", err
+	}
+	if err := guard.complete(ctx, id); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+func (c *AssetContract) createAsset(ctx contractapi.TransactionContextInterface, function string, id string, owner string, rawValue string) error {
+	asset, err := c.newAsset(ctx, id, owner, rawValue)
 	if err != nil {
 		return err
 	}
+	return c.saveAsset(ctx, function, nil, asset)
+}
+
+// newAsset validates the arguments of an asset creation and returns the
+// asset to store.
+func (c *AssetContract) newAsset(ctx contractapi.TransactionContextInterface, id string, owner string, rawValue string) (*Asset, error) {
+	cfg, err := readConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if id, err = cfg.normalizeID("id", id); err != nil {
+		return nil, err
+	}
+	if owner, err = cfg.normalizeOwner("owner", owner); err != nil {
+		return nil, err
+	}
+	if err := cfg.checkOwnerMSP(ctx); err != nil {
+		return nil, err
+	}
+	if err := cfg.checkID(id); err != nil {
+		return nil, err
+	}
+	value, err := parseValue("value", rawValue)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.checkValue("value", value); err != nil {
+		return nil, err
+	}
+
+	exists, err := c.AssetExists(ctx, id)
+	if err != nil {
+		return nil, err
+	}
 	if exists {
-		return fmt.Errorf("asset %s already exists", id)
+		return nil, fmt.Errorf("asset %s already exists", id)
 	}
 
 	now, err := txTimeRFC3339(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	asset := Asset{
+	return &Asset{
 		ID:        id,
 		Owner:     owner,
 		Value:     value,
 		CreatedAt: now,
 		UpdatedAt: now,
 		Version:   1,
-	}
+	}, nil
+}
 
-	b, err := json.Marshal(asset)
-	if err != nil {
-		return fmt.Errorf("marshal asset: %w", err)
+// autoAssetID derives an asset ID from the transaction ID. Transaction IDs are
+// unique per channel, so IDs from different orgs and clients never collide.
+func (c *AssetContract) autoAssetID(txID string) string {
+	prefix := c.AutoIDPrefix
+	if prefix == "" {
+		prefix = defaultAutoIDPrefix
 	}
-
-	return ctx.GetStub().PutState(id, b)
+	if len(txID) > autoIDLength {
+		txID = txID[:autoIDLength]
+	}
+	return prefix + "-" + txID
 }
 
 func (c *AssetContract) ReadAsset(ctx contractapi.TransactionContextInterface, id string) (*Asset, error) {
-	id = strings.TrimSpace(id)
-	if id == "" {
-		return nil, errors.New("id is required")
+	id, err := existingID("id", id)
+	if err != nil {
+		return nil, err
 	}
+	return readAsset(ctx, id)
+}
 
+// readAsset reads an asset by an ID taken from the ledger, which needs no
+// validation.
+func readAsset(ctx contractapi.TransactionContextInterface, id string) (*Asset, error) {
 	b, err := ctx.GetStub().GetState(id)
 	if err != nil {
 		return nil, fmt.Errorf("get state: %w", err)
@@ -80,70 +187,275 @@ func (c *AssetContract) ReadAsset(ctx contractapi.TransactionContextInterface, i
 }
 
 func (c *AssetContract) UpdateAssetOwner(ctx contractapi.TransactionContextInterface, id string, newOwner string) error {
-	newOwner = strings.TrimSpace(newOwner)
-	if newOwner == "" {
-		return errors.New("newOwner is required")
+	guard, err := beginRequest(ctx, "UpdateAssetOwner", id, newOwner)
+	if err != nil {
+		return err
+	}
+	if _, ok := guard.replayed(); ok {
+		return nil
+	}
+
+	cfg, err := readConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if newOwner, err = cfg.normalizeOwner("newOwner", newOwner); err != nil {
+		return err
+	}
+	if err := cfg.checkOwnerMSP(ctx); err != nil {
+		return err
 	}
 
 	asset, err := c.ReadAsset(ctx, id)
 	if err != nil {
 		return err
 	}
+	a, err := authorize(ctx, asset, RightTransfer)
+	if err != nil {
+		return err
+	}
+	owner := asset.Owner
+	if err := c.transferAsset(ctx, "UpdateAssetOwner", asset, newOwner); err != nil {
+		return err
+	}
+	if err := emitOperation(ctx, "UpdateAssetOwner", asset, owner, a); err != nil {
+		return err
+	}
+	return guard.complete(ctx, "")
+}
+
+// transferAsset applies the checks of an ownership change and moves asset,
+// together with its components, to newOwner.
+func (c *AssetContract) transferAsset(ctx contractapi.TransactionContextInterface, function string, asset *Asset, newOwner string) error {
+	if err := checkNoOpenDispute(ctx, asset.ID); err != nil {
+		return err
+	}
+	return c.reassignAsset(ctx, function, asset, newOwner)
+}
+
+// reassignAsset is transferAsset without the open dispute check, for the
+// resolution of the dispute itself.
+func (c *AssetContract) reassignAsset(ctx contractapi.TransactionContextInterface, function string, asset *Asset, newOwner string) error {
+	if err := requireVerifiedOwner(ctx, newOwner); err != nil {
+		return err
+	}
+	if asset.ParentID != "" {
+		return fmt.Errorf("asset %s is a component of %s; transfer the parent asset instead", asset.ID, asset.ParentID)
+	}
+	if len(asset.Shares) > 0 {
+		return fmt.Errorf("asset %s is held in shares; use TransferShares", asset.ID)
+	}
+	if err := checkLeaseAllows(ctx, asset.ID, true); err != nil {
+		return err
+	}
+	if err := checkComponentsTransferable(ctx, asset.ID); err != nil {
+		return err
+	}
+	return c.changeOwner(ctx, function, asset, newOwner)
+}
+
+// checkComponentsTransferable applies the share, lease and dispute checks of
+// a direct transfer to every component of parentID, as they change owner with
+// it.
+func checkComponentsTransferable(ctx contractapi.TransactionContextInterface, parentID string) error {
+	children, err := componentIDs(ctx, parentID)
+	if err != nil {
+		return err
+	}
+	for _, childID := range children {
+		child, err := readAsset(ctx, childID)
+		if err != nil {
+			return err
+		}
+		if len(child.Shares) > 0 {
+			return fmt.Errorf("component %s of %s is held in shares", child.ID, parentID)
+		}
+		if err := checkLeaseAllows(ctx, child.ID, true); err != nil {
+			return err
+		}
+		if err := checkNoOpenDispute(ctx, child.ID); err != nil {
+			return err
+		}
+		if err := checkComponentsTransferable(ctx, child.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// changeOwner sets the owner of asset and, recursively, of its components.
+func (c *AssetContract) changeOwner(ctx contractapi.TransactionContextInterface, function string, asset *Asset, newOwner string) error {
+	if err := checkNotFrozen(ctx, asset.ID); err != nil {
+		return err
+	}
 
 	now, err := txTimeRFC3339(ctx)
 	if err != nil {
 		return err
 	}
 
+	before := *asset
 	asset.Owner = newOwner
 	asset.UpdatedAt = now
 	asset.Version++
 
-	b, err := json.Marshal(asset)
+	if err := c.saveAsset(ctx, function, &before, asset); err != nil {
+		return err
+	}
+	// A token approval is granted by the owner and must not outlive the
+	// ownership it was granted under.
+	if err := clearApproval(ctx, asset.ID); err != nil {
+		return err
+	}
+
+	children, err := componentIDs(ctx, asset.ID)
 	if err != nil {
-		return fmt.Errorf("marshal asset: %w", err)
+		return err
 	}
-	return ctx.GetStub().PutState(asset.ID, b)
+	for _, childID := range children {
+		child, err := readAsset(ctx, childID)
+		if err != nil {
+			return err
+		}
+		if err := c.changeOwner(ctx, function, child, newOwner); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func (c *AssetContract) UpdateAssetValue(ctx contractapi.TransactionContextInterface, id string, newValue int64) error {
-	if newValue < 0 {
-		return errors.New("newValue must be >= 0")
+func (c *AssetContract) UpdateAssetValue(ctx contractapi.TransactionContextInterface, id string, newValue string) error {
+	guard, err := beginRequest(ctx, "UpdateAssetValue", id, newValue)
+	if err != nil {
+		return err
+	}
+	if _, ok := guard.replayed(); ok {
+		return nil
+	}
+
+	cfg, err := readConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if err := cfg.checkOwnerMSP(ctx); err != nil {
+		return err
+	}
+	value, err := parseValue("newValue", newValue)
+	if err != nil {
+		return err
+	}
+	if err := cfg.checkValue("newValue", value); err != nil {
+		return err
 	}
 
 	asset, err := c.ReadAsset(ctx, id)
 	if err != nil {
 		return err
 	}
+	a, err := authorize(ctx, asset, RightUpdateValue)
+	if err != nil {
+		return err
+	}
+	if err := checkNotShared(asset); err != nil {
+		return err
+	}
+	if err := checkNotFrozen(ctx, asset.ID); err != nil {
+		return err
+	}
+	if concealed(asset) {
+		return fmt.Errorf("asset %s has a concealed value; use CommitAssetValue or RevealValue", asset.ID)
+	}
+	if err := checkNotAppraised(ctx, asset.ID); err != nil {
+		return err
+	}
 
 	now, err := txTimeRFC3339(ctx)
 	if err != nil {
 		return err
 	}
 
-	asset.Value = newValue
+	before := *asset
+	asset.Value = value
+	asset.ValuationID = ""
+	// A public value supersedes a revealed commitment.
+	asset.ValueCommitment = ""
+	asset.ValueSalt = ""
+	asset.ValueCollection = ""
 	asset.UpdatedAt = now
 	asset.Version++
 
-	b, err := json.Marshal(asset)
-	if err != nil {
-		return fmt.Errorf("marshal asset: %w", err)
+	if err := c.saveAsset(ctx, "UpdateAssetValue", &before, asset); err != nil {
+		return err
+	}
+	if err := emitOperation(ctx, "UpdateAssetValue", asset, asset.Owner, a); err != nil {
+		return err
 	}
-	return ctx.GetStub().PutState(asset.ID, b)
+	return guard.complete(ctx, "")
 }
 
 func (c *AssetContract) DeleteAsset(ctx contractapi.TransactionContextInterface, id string) error {
-	_, err := c.ReadAsset(ctx, id)
+	guard, err := beginRequest(ctx, "DeleteAsset", id)
 	if err != nil {
 		return err
 	}
-	return ctx.GetStub().DelState(id)
+	if _, ok := guard.replayed(); ok {
+		return nil
+	}
+
+	cfg, err := readConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if err := cfg.checkOwnerMSP(ctx); err != nil {
+		return err
+	}
+
+	asset, err := c.ReadAsset(ctx, id)
+	if err != nil {
+		return err
+	}
+	a, err := authorize(ctx, asset, RightDelete)
+	if err != nil {
+		return err
+	}
+	if err := checkNotShared(asset); err != nil {
+		return err
+	}
+	if err := checkNotFrozen(ctx, asset.ID); err != nil {
+		return err
+	}
+	if err := checkNoOpenDispute(ctx, asset.ID); err != nil {
+		return err
+	}
+	children, err := componentIDs(ctx, asset.ID)
+	if err != nil {
+		return err
+	}
+	if len(children) > 0 {
+		return fmt.Errorf("asset %s still has %d components; detach them first", asset.ID, len(children))
+	}
+	if err := checkLeaseAllows(ctx, asset.ID, false); err != nil {
+		return err
+	}
+	if err := c.saveAsset(ctx, "DeleteAsset", asset, nil); err != nil {
+		return err
+	}
+	if err := clearApproval(ctx, asset.ID); err != nil {
+		return err
+	}
+	if err := clearLease(ctx, asset.ID); err != nil {
+		return err
+	}
+	if err := emitOperation(ctx, "DeleteAsset", asset, asset.Owner, a); err != nil {
+		return err
+	}
+	return guard.complete(ctx, "")
 }
 
 func (c *AssetContract) AssetExists(ctx contractapi.TransactionContextInterface, id string) (bool, error) {
-	id = strings.TrimSpace(id)
-	if id == "" {
-		return false, errors.New("id is required")
+	id, err := existingID("id", id)
+	if err != nil {
+		return false, err
 	}
 
 	b, err := ctx.GetStub().GetState(id)
@@ -175,11 +487,43 @@ func (c *AssetContract) GetAllAssets(ctx contractapi.TransactionContextInterface
 	return out, nil
 }
 
+// saveAsset is the single write path for assets. It stores after (or deletes
+// before when after is nil), maintains the secondary indexes and appends the
+// audit record for the mutation.
+func (c *AssetContract) saveAsset(ctx contractapi.TransactionContextInterface, function string, before, after *Asset) error {
+	if err := updateIndexes(ctx, before, after); err != nil {
+		return err
+	}
+
+	if after == nil {
+		if err := ctx.GetStub().DelState(before.ID); err != nil {
+			return fmt.Errorf("delete state: %w", err)
+		}
+		return recordAudit(ctx, function, before, nil)
+	}
+
+	b, err := json.Marshal(after)
+	if err != nil {
+		return fmt.Errorf("marshal asset: %w", err)
+	}
+	if err := ctx.GetStub().PutState(after.ID, b); err != nil {
+		return fmt.Errorf("put state: %w", err)
+	}
+	return recordAudit(ctx, function, before, after)
+}
+
 func txTimeRFC3339(ctx contractapi.TransactionContextInterface) (string, error) {
-	ts, err := ctx.GetStub().GetTxTimestamp()
+	t, err := txTime(ctx)
 	if err != nil {
-		return "", fmt.Errorf("get tx timestamp: %w", err)
+		return "", err
 	}
-	t := time.Unix(int64(ts.Seconds), int64(ts.Nanos)).UTC()
 	return t.Format(time.RFC3339Nano), nil
 }
+
+func txTime(ctx contractapi.TransactionContextInterface) (time.Time, error) {
+	ts, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("get tx timestamp: %w", err)
+	}
+	return time.Unix(int64(ts.Seconds), int64(ts.Nanos)).UTC(), nil
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCreateAsset(t *testing.T) {
+	e := newTestEnv(t)
+	asset := e.createAsset("asset1", "Alice", "125.5 eur")
+
+	if asset.Owner != "Alice" || asset.Version != 1 {
+		t.Fatalf("unexpected asset %+v", asset)
+	}
+	if asset.Value != (Money{Amount: 12550, Currency: "EUR", Decimals: 2}) {
+		t.Fatalf("value = %+v", asset.Value)
+	}
+	if asset.CreatedAt == "" || asset.CreatedAt != asset.UpdatedAt {
+		t.Fatalf("timestamps = %s, %s", asset.CreatedAt, asset.UpdatedAt)
+	}
+}
+
+func TestCreateAssetErrors(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("asset1", "Alice", "10 EUR")
+	e.verifyOwners("Bob")
+	mustOK(t, e.registerOwner(alice, "Mallory", "Mallory", ""))
+
+	tests := []struct {
+		name, id, owner, value, err string
+	}{
+		{"empty id", "  ", "Alice", "10 EUR", "id is required"},
+		{"empty owner", "asset2", " ", "10 EUR", "owner is required"},
+		{"unregistered owner", "asset2", "Nobody", "10 EUR", "owner Nobody is not registered"},
+		{"unverified owner", "asset2", "Mallory", "10 EUR", "KYC status pending"},
+		{"duplicate", "asset1", "Bob", "10 EUR", "asset asset1 already exists"},
+		{"no currency", "asset2", "Bob", "10", "must be an amount followed by a currency"},
+		{"unknown currency", "asset2", "Bob", "10 ZZZ", "unsupported currency"},
+		{"negative", "asset2", "Bob", "-1 EUR", "must be >= 0"},
+		{"too precise", "asset2", "Bob", "1.001 EUR", "at most 2 decimal places"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			wantErr(t, e.assets.CreateAsset(e.tx(alice), tc.id, tc.owner, tc.value), tc.err)
+		})
+	}
+}
+
+func TestCreateAssetReplaysRequest(t *testing.T) {
+	e := newTestEnv(t)
+	e.verifyOwners("Alice")
+
+	mustOK(t, e.assets.CreateAsset(e.tx(alice).withTransient(requestIDKey, "req-1"), "asset1", "Alice", "10 EUR"))
+	// A retry with the same request ID succeeds without creating twice.
+	mustOK(t, e.assets.CreateAsset(e.tx(alice).withTransient(requestIDKey, "req-1"), "asset1", "Alice", "10 EUR"))
+	if v := e.readAsset("asset1").Version; v != 1 {
+		t.Fatalf("version = %d, want 1", v)
+	}
+
+	err := e.assets.CreateAsset(e.tx(alice).withTransient(requestIDKey, "req-1"), "asset2", "Alice", "10 EUR")
+	wantErr(t, err, "request req-1 was already used for a different CreateAsset call")
+}
+
+func TestReplayNeedsTheSameSubmitter(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("asset1", alice.id(), "10 EUR")
+	e.verifyOwners(bob.id())
+
+	mustOK(t, e.assets.UpdateAssetOwner(e.tx(alice).withTransient(requestIDKey, "req-1"), "asset1", bob.id()))
+	// Another identity of the same org reusing the request ID is authorized
+	// like any other call rather than handed the committed result.
+	wantErr(t, e.assets.UpdateAssetOwner(e.tx(carol).withTransient(requestIDKey, "req-1"), "asset1", bob.id()), "only the owner of asset asset1")
+	mustOK(t, e.assets.UpdateAssetOwner(e.tx(alice).withTransient(requestIDKey, "req-1"), "asset1", bob.id()))
+}
+
+func TestCreateAssetAuto(t *testing.T) {
+	e := newTestEnv(t)
+	e.verifyOwners("Alice")
+
+	ctx := e.tx(alice).withTransient(requestIDKey, "req-auto")
+	id, err := e.assets.CreateAssetAuto(ctx, "Alice", "5 USD")
+	mustOK(t, err)
+	if want := "asset-" + ctx.stub.txID[:autoIDLength]; id != want {
+		t.Fatalf("id = %s, want %s", id, want)
+	}
+	if e.readAsset(id).Owner != "Alice" {
+		t.Fatal("asset not stored under the generated ID")
+	}
+
+	replayed, err := e.assets.CreateAssetAuto(e.tx(alice).withTransient(requestIDKey, "req-auto"), "Alice", "5 USD")
+	mustOK(t, err)
+	if replayed != id {
+		t.Fatalf("replayed id = %s, want %s", replayed, id)
+	}
+
+	e.assets.AutoIDPrefix = "inv"
+	id, err = e.assets.CreateAssetAuto(e.tx(alice), "Alice", "5 USD")
+	mustOK(t, err)
+	if !strings.HasPrefix(id, "inv-") {
+		t.Fatalf("id = %s, want prefix inv-", id)
+	}
+
+	_, err = e.assets.CreateAssetAuto(e.tx(alice), "Nobody", "5 USD")
+	wantErr(t, err, "not registered")
+}
+
+func TestReadAsset(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("asset1", "Alice", "10 EUR")
+
+	asset, err := e.assets.ReadAsset(e.tx(bob), " asset1 ")
+	mustOK(t, err)
+	if asset.ID != "asset1" {
+		t.Fatalf("id = %s", asset.ID)
+	}
+
+	_, err = e.assets.ReadAsset(e.tx(bob), "missing")
+	wantErr(t, err, "asset missing not found")
+	_, err = e.assets.ReadAsset(e.tx(bob), "")
+	wantErr(t, err, "id is required")
+}
+
+func TestUpdateAssetOwner(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("asset1", "Alice", "10 EUR")
+	e.verifyOwners("Bob")
+
+	mustOK(t, e.assets.UpdateAssetOwner(e.tx(carol), "asset1", " Bob "))
+	asset := e.readAsset("asset1")
+	if asset.Owner != "Bob" || asset.Version != 2 {
+		t.Fatalf("unexpected asset %+v", asset)
+	}
+
+	wantErr(t, e.assets.UpdateAssetOwner(e.tx(carol), "asset1", ""), "newOwner is required")
+	wantErr(t, e.assets.UpdateAssetOwner(e.tx(carol), "asset1", "Nobody"), "not registered")
+	wantErr(t, e.assets.UpdateAssetOwner(e.tx(carol), "missing", "Bob"), "not found")
+}
+
+func TestUpdateAssetOwnerIdentityOwned(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("asset1", alice.id(), "10 EUR")
+	e.verifyOwners(bob.id())
+
+	err := e.assets.UpdateAssetOwner(e.tx(carol), "asset1", bob.id())
+	wantErr(t, err, "only the owner of asset asset1 or an operator with the transfer right")
+
+	ctx := e.tx(alice)
+	mustOK(t, e.assets.UpdateAssetOwner(ctx, "asset1", bob.id()))
+	var ev OperationEvent
+	if name := eventOf(t, ctx, &ev); name != "AssetOperation" {
+		t.Fatalf("event = %s", name)
+	}
+	if ev.Actor != alice.id() || ev.Owner != alice.id() || ev.Operator {
+		t.Fatalf("unexpected event %+v", ev)
+	}
+}
+
+func TestUpdateAssetValue(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("asset1", "Alice", "10 EUR")
+
+	mustOK(t, e.assets.UpdateAssetValue(e.tx(alice), "asset1", "99.99 EUR"))
+	asset := e.readAsset("asset1")
+	if asset.Value.Amount != 9999 || asset.Version != 2 {
+		t.Fatalf("unexpected asset %+v", asset)
+	}
+
+	wantErr(t, e.assets.UpdateAssetValue(e.tx(alice), "asset1", "abc"), "newValue")
+	wantErr(t, e.assets.UpdateAssetValue(e.tx(alice), "missing", "1 EUR"), "not found")
+
+	e.createAsset("asset2", alice.id(), "10 EUR")
+	wantErr(t, e.assets.UpdateAssetValue(e.tx(bob), "asset2", "1 EUR"), "updateValue right")
+}
+
+func TestDeleteAsset(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("asset1", "Alice", "10 EUR")
+
+	mustOK(t, e.assets.DeleteAsset(e.tx(alice), "asset1"))
+	exists, err := e.assets.AssetExists(e.tx(alice), "asset1")
+	mustOK(t, err)
+	if exists {
+		t.Fatal("asset still exists")
+	}
+	wantErr(t, e.assets.DeleteAsset(e.tx(alice), "asset1"), "not found")
+
+	e.createAsset("parent", "Alice", "10 EUR")
+	e.createAsset("child", "Alice", "1 EUR")
+	mustOK(t, e.assets.AttachComponent(e.tx(alice), "parent", "child"))
+	wantErr(t, e.assets.DeleteAsset(e.tx(alice), "parent"), "still has 1 components")
+
+	e.createAsset("owned", alice.id(), "1 EUR")
+	wantErr(t, e.assets.DeleteAsset(e.tx(bob), "owned"), "delete right")
+}
+
+func TestAssetExists(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("asset1", "Alice", "10 EUR")
+
+	for id, want := range map[string]bool{"asset1": true, " asset1 ": true, "asset2": false} {
+		got, err := e.assets.AssetExists(e.tx(bob), id)
+		mustOK(t, err)
+		if got != want {
+			t.Errorf("AssetExists(%q) = %v, want %v", id, got, want)
+		}
+	}
+	_, err := e.assets.AssetExists(e.tx(bob), "")
+	wantErr(t, err, "id is required")
+}
+
+func TestGetAllAssets(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("b", "Alice", "1 EUR")
+	e.createAsset("a", "Alice", "2 EUR")
+
+	// Index, audit and registry entries live under composite keys and must
+	// not show up as assets.
+	assets, err := e.assets.GetAllAssets(e.tx(bob))
+	mustOK(t, err)
+	if len(assets) != 2 || assets[0].ID != "a" || assets[1].ID != "b" {
+		t.Fatalf("unexpected assets %+v", assets)
+	}
+}
+
+func TestTxTimeRFC3339(t *testing.T) {
+	e := newTestEnv(t)
+	e.ledger.clock = time.Date(2024, 5, 1, 12, 0, 0, 500, time.UTC)
+
+	ctx := e.tx(alice)
+	got, err := txTimeRFC3339(ctx)
+	mustOK(t, err)
+	if want := "2024-05-01T12:00:01.0000005Z"; got != want {
+		t.Fatalf("txTimeRFC3339 = %s, want %s", got, want)
+	}
+}
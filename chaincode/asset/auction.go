@@ -0,0 +1,579 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	auctionObjectType = "auction"
+	bidObjectType     = "bid"
+	// bidTransientKey is the transient data key carrying a bid, see bid.
+	bidTransientKey = "bid"
+
+	auctionOpen      = "open"
+	auctionClosed    = "closed"
+	auctionEnded     = "ended"
+	auctionCancelled = "cancelled"
+)
+
+// Auction is a sealed-bid auction of an asset. Bids are collected while the
+// auction is open, revealed once it is closed, and the highest revealed bid
+// wins when the seller ends it. The seller may cancel an auction that has not
+// ended.
+type Auction struct {
+	ID        string         `json:"id"`
+	AssetID   string         `json:"assetId"`
+	Seller    string         `json:"seller"`
+	Currency  string         `json:"currency"`
+	Status    string         `json:"status"`
+	CreatedAt string         `json:"createdAt"`
+	Bids      []*SealedBid   `json:"bids"`
+	Revealed  []*RevealedBid `json:"revealed"`
+	Winner    string         `json:"winner,omitempty" metadata:",optional"`
+	Price     *Money         `json:"price,omitempty" metadata:",optional"`
+	// PassedOver lists the bidders of higher bids that could not receive
+	// the asset when the auction ended.
+	PassedOver []string `json:"passedOver,omitempty" metadata:",optional"`
+}
+
+// SealedBid locates a bid stored in the bidder's implicit org collection. Only
+// the hash of the bid is visible on the channel until it is revealed.
+type SealedBid struct {
+	Bidder     string `json:"bidder"`
+	Collection string `json:"collection"`
+	TxID       string `json:"txId"`
+}
+
+type RevealedBid struct {
+	Bidder string `json:"bidder"`
+	Price  Money  `json:"price"`
+	TxID   string `json:"txId"`
+}
+
+// bid is the content a bidder passes as transient data when submitting and
+// again, byte for byte, when revealing. The salt keeps the on-ledger hash from
+// being brute-forced over likely prices.
+type bid struct {
+	Price string `json:"price"`
+	Salt  string `json:"salt"`
+}
+
+// CreateAuction puts an asset up for auction. The invoker must be the owner
+// or an operator holding the transfer right; bids are accepted in currency
+// only. Frozen or disputed assets cannot be put up for auction.
+func (c *AssetContract) CreateAuction(ctx contractapi.TransactionContextInterface, auctionID string, assetID string, currency string) error {
+	guard, err := beginRequest(ctx, "CreateAuction", auctionID, assetID, currency)
+	if err != nil {
+		return err
+	}
+	if _, ok := guard.replayed(); ok {
+		return nil
+	}
+
+	if err := requireOwnerMSP(ctx); err != nil {
+		return err
+	}
+
+	auctionID, err = validID(ctx, "auctionID", auctionID)
+	if err != nil {
+		return err
+	}
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if _, ok := currencyDecimals[currency]; !ok {
+		return fmt.Errorf("unsupported currency %q", currency)
+	}
+
+	asset, err := c.ReadAsset(ctx, assetID)
+	if err != nil {
+		return err
+	}
+	a, err := authorize(ctx, asset, RightTransfer)
+	if err != nil {
+		return err
+	}
+	if err := checkNotShared(asset); err != nil {
+		return err
+	}
+	if err := checkNotFrozen(ctx, asset.ID); err != nil {
+		return err
+	}
+	if err := checkNoOpenDispute(ctx, asset.ID); err != nil {
+		return err
+	}
+
+	existing, err := readAuction(ctx, auctionID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("auction %s already exists", auctionID)
+	}
+
+	now, err := txTimeRFC3339(ctx)
+	if err != nil {
+		return err
+	}
+
+	auction := &Auction{
+		ID:        auctionID,
+		AssetID:   asset.ID,
+		Seller:    asset.Owner,
+		Currency:  currency,
+		Status:    auctionOpen,
+		CreatedAt: now,
+		Bids:      []*SealedBid{},
+		Revealed:  []*RevealedBid{},
+	}
+	if err := putAuction(ctx, auction); err != nil {
+		return err
+	}
+	if err := recordRelatedAudit(ctx, "CreateAuction", asset.ID, "auction", nil, auction); err != nil {
+		return err
+	}
+	if err := emitOperation(ctx, "CreateAuction", asset, asset.Owner, a); err != nil {
+		return err
+	}
+	return guard.complete(ctx, "")
+}
+
+// SubmitBid stores the transient bid in the invoker's implicit org collection
+// and returns the bid's transaction ID, which is needed to reveal it.
+func (c *AssetContract) SubmitBid(ctx contractapi.TransactionContextInterface, auctionID string) (string, error) {
+	guard, err := beginRequest(ctx, "SubmitBid", auctionID)
+	if err != nil {
+		return "", err
+	}
+	if result, ok := guard.replayed(); ok {
+		return result, nil
+	}
+
+	if err := requireOwnerMSP(ctx); err != nil {
+		return "", err
+	}
+
+	auction, err := c.GetAuction(ctx, auctionID)
+	if err != nil {
+		return "", err
+	}
+	if auction.Status != auctionOpen {
+		return "", fmt.Errorf("auction %s is %s and no longer accepts bids", auction.ID, auction.Status)
+	}
+
+	bidder, err := submitterID(ctx)
+	if err != nil {
+		return "", err
+	}
+	if bidder == auction.Seller {
+		return "", errors.New("the seller cannot bid in their own auction")
+	}
+
+	raw, err := transientBid(ctx)
+	if err != nil {
+		return "", err
+	}
+	if _, err := parseBid(raw, auction.Currency); err != nil {
+		return "", err
+	}
+
+	collection, err := implicitCollection(ctx)
+	if err != nil {
+		return "", err
+	}
+	txID := ctx.GetStub().GetTxID()
+	key, err := ctx.GetStub().CreateCompositeKey(bidObjectType, []string{auction.ID, txID})
+	if err != nil {
+		return "", fmt.Errorf("create bid key: %w", err)
+	}
+	if err := ctx.GetStub().PutPrivateData(collection, key, raw); err != nil {
+		return "", fmt.Errorf("put private data: %w", err)
+	}
+
+	before := *auction
+	auction.Bids = append(auction.Bids, &SealedBid{Bidder: bidder, Collection: collection, TxID: txID})
+	if err := putAuction(ctx, auction); err != nil {
+		return "", err
+	}
+	if err := recordRelatedAudit(ctx, "SubmitBid", auction.AssetID, "auction", &before, auction); err != nil {
+		return "", err
+	}
+	if err := emitAuctionOperation(ctx, "SubmitBid", auction, &actor{id: bidder}); err != nil {
+		return "", err
+	}
+	if err := guard.complete(ctx, txID); err != nil {
+		return "", err
+	}
+	return txID, nil
+}
+
+// CloseAuction stops accepting bids so that bidders can reveal them.
+func (c *AssetContract) CloseAuction(ctx contractapi.TransactionContextInterface, auctionID string) error {
+	guard, err := beginRequest(ctx, "CloseAuction", auctionID)
+	if err != nil {
+		return err
+	}
+	if _, ok := guard.replayed(); ok {
+		return nil
+	}
+
+	if err := requireOwnerMSP(ctx); err != nil {
+		return err
+	}
+
+	auction, err := c.GetAuction(ctx, auctionID)
+	if err != nil {
+		return err
+	}
+	a, err := requireSeller(ctx, auction)
+	if err != nil {
+		return err
+	}
+	if auction.Status != auctionOpen {
+		return fmt.Errorf("auction %s is %s, not %s", auction.ID, auction.Status, auctionOpen)
+	}
+
+	before := *auction
+	auction.Status = auctionClosed
+	if err := putAuction(ctx, auction); err != nil {
+		return err
+	}
+	if err := recordRelatedAudit(ctx, "CloseAuction", auction.AssetID, "auction", &before, auction); err != nil {
+		return err
+	}
+	if err := emitAuctionOperation(ctx, "CloseAuction", auction, a); err != nil {
+		return err
+	}
+	return guard.complete(ctx, "")
+}
+
+// RevealBid discloses a sealed bid of the invoker. The transient bid must hash
+// to the value committed when the bid was submitted.
+func (c *AssetContract) RevealBid(ctx contractapi.TransactionContextInterface, auctionID string, bidTxID string) error {
+	guard, err := beginRequest(ctx, "RevealBid", auctionID, bidTxID)
+	if err != nil {
+		return err
+	}
+	if _, ok := guard.replayed(); ok {
+		return nil
+	}
+
+	if err := requireOwnerMSP(ctx); err != nil {
+		return err
+	}
+
+	auction, err := c.GetAuction(ctx, auctionID)
+	if err != nil {
+		return err
+	}
+	if auction.Status != auctionClosed {
+		return fmt.Errorf("bids can only be revealed while auction %s is %s", auction.ID, auctionClosed)
+	}
+
+	var sealed *SealedBid
+	for _, b := range auction.Bids {
+		if b.TxID == bidTxID {
+			sealed = b
+		}
+	}
+	if sealed == nil {
+		return fmt.Errorf("auction %s has no bid %s", auction.ID, bidTxID)
+	}
+	for _, r := range auction.Revealed {
+		if r.TxID == bidTxID {
+			return fmt.Errorf("bid %s is already revealed", bidTxID)
+		}
+	}
+
+	bidder, err := submitterID(ctx)
+	if err != nil {
+		return err
+	}
+	if bidder != sealed.Bidder {
+		return errors.New("only the bidder may reveal a bid")
+	}
+
+	raw, err := transientBid(ctx)
+	if err != nil {
+		return err
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(bidObjectType, []string{auction.ID, sealed.TxID})
+	if err != nil {
+		return fmt.Errorf("create bid key: %w", err)
+	}
+	committed, err := ctx.GetStub().GetPrivateDataHash(sealed.Collection, key)
+	if err != nil {
+		return fmt.Errorf("get private data hash: %w", err)
+	}
+	sum := sha256.Sum256(raw)
+	if !bytes.Equal(committed, sum[:]) {
+		return fmt.Errorf("revealed bid does not match sealed bid %s", bidTxID)
+	}
+
+	price, err := parseBid(raw, auction.Currency)
+	if err != nil {
+		return err
+	}
+
+	before := *auction
+	auction.Revealed = append(auction.Revealed, &RevealedBid{Bidder: bidder, Price: price, TxID: sealed.TxID})
+	if err := putAuction(ctx, auction); err != nil {
+		return err
+	}
+	if err := recordRelatedAudit(ctx, "RevealBid", auction.AssetID, "auction", &before, auction); err != nil {
+		return err
+	}
+	if err := emitAuctionOperation(ctx, "RevealBid", auction, &actor{id: bidder}); err != nil {
+		return err
+	}
+	return guard.complete(ctx, "")
+}
+
+// EndAuction transfers the asset to the highest revealed bid, with the checks
+// of UpdateAssetOwner. Bids that were not revealed are ignored and ties go to
+// the bid revealed first. Bidders who may not hold the asset, because they are
+// not KYC verified, are passed over for the next bid. When the asset itself
+// cannot be transferred, for example while it is frozen or disputed, the
+// auction stays closed and the seller may retry or cancel it.
+func (c *AssetContract) EndAuction(ctx contractapi.TransactionContextInterface, auctionID string) error {
+	guard, err := beginRequest(ctx, "EndAuction", auctionID)
+	if err != nil {
+		return err
+	}
+	if _, ok := guard.replayed(); ok {
+		return nil
+	}
+
+	cfg, err := readConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if err := cfg.checkOwnerMSP(ctx); err != nil {
+		return err
+	}
+	auction, err := c.GetAuction(ctx, auctionID)
+	if err != nil {
+		return err
+	}
+	a, err := requireSeller(ctx, auction)
+	if err != nil {
+		return err
+	}
+	if auction.Status != auctionClosed {
+		return fmt.Errorf("auction %s is %s, not %s", auction.ID, auction.Status, auctionClosed)
+	}
+
+	before := *auction
+	ranked := make([]*RevealedBid, len(auction.Revealed))
+	copy(ranked, auction.Revealed)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Price.Amount > ranked[j].Price.Amount
+	})
+	var winner *RevealedBid
+	for _, r := range ranked {
+		err := requireVerifiedOwner(ctx, r.Bidder)
+		if errors.Is(err, errOwnerNotVerified) {
+			auction.PassedOver = append(auction.PassedOver, r.Bidder)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		winner = r
+		break
+	}
+
+	auction.Status = auctionEnded
+	if winner != nil {
+		asset, err := readAsset(ctx, auction.AssetID)
+		if err != nil {
+			return err
+		}
+		if asset.Owner != auction.Seller {
+			return fmt.Errorf("asset %s changed owner during auction %s", asset.ID, auction.ID)
+		}
+		if err := c.transferAsset(ctx, "EndAuction", asset, winner.Bidder); err != nil {
+			return err
+		}
+		auction.Winner = winner.Bidder
+		auction.Price = &winner.Price
+	}
+
+	if err := putAuction(ctx, auction); err != nil {
+		return err
+	}
+	// The transfer to the winner is audited already, and an asset has one
+	// audit record per transaction.
+	if winner == nil {
+		if err := recordRelatedAudit(ctx, "EndAuction", auction.AssetID, "auction", &before, auction); err != nil {
+			return err
+		}
+	}
+	if err := emitAuctionOperation(ctx, "EndAuction", auction, a); err != nil {
+		return err
+	}
+	return guard.complete(ctx, "")
+}
+
+// CancelAuction withdraws an auction that has not ended, for example when the
+// asset could not be transferred to the winner. Sealed bids stay in the
+// bidders' collections and are never revealed.
+func (c *AssetContract) CancelAuction(ctx contractapi.TransactionContextInterface, auctionID string) error {
+	guard, err := beginRequest(ctx, "CancelAuction", auctionID)
+	if err != nil {
+		return err
+	}
+	if _, ok := guard.replayed(); ok {
+		return nil
+	}
+
+	if err := requireOwnerMSP(ctx); err != nil {
+		return err
+	}
+
+	auction, err := c.GetAuction(ctx, auctionID)
+	if err != nil {
+		return err
+	}
+	a, err := requireSeller(ctx, auction)
+	if err != nil {
+		return err
+	}
+	if auction.Status != auctionOpen && auction.Status != auctionClosed {
+		return fmt.Errorf("auction %s is %s and can no longer be cancelled", auction.ID, auction.Status)
+	}
+
+	before := *auction
+	auction.Status = auctionCancelled
+	if err := putAuction(ctx, auction); err != nil {
+		return err
+	}
+	if err := recordRelatedAudit(ctx, "CancelAuction", auction.AssetID, "auction", &before, auction); err != nil {
+		return err
+	}
+	if err := emitAuctionOperation(ctx, "CancelAuction", auction, a); err != nil {
+		return err
+	}
+	return guard.complete(ctx, "")
+}
+
+func (c *AssetContract) GetAuction(ctx contractapi.TransactionContextInterface, auctionID string) (*Auction, error) {
+	auctionID, err := existingID("auctionID", auctionID)
+	if err != nil {
+		return nil, err
+	}
+	auction, err := readAuction(ctx, auctionID)
+	if err != nil {
+		return nil, err
+	}
+	if auction == nil {
+		return nil, fmt.Errorf("auction %s not found", auctionID)
+	}
+	return auction, nil
+}
+
+// requireSeller rejects invokers other than the seller and its operators
+// holding the transfer right over the auctioned asset. As in authorize, label
+// sellers carry no identity and any invoker may manage their auctions.
+func requireSeller(ctx contractapi.TransactionContextInterface, auction *Auction) (*actor, error) {
+	id, err := submitterID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if id == auction.Seller || !isIdentity(auction.Seller) {
+		return &actor{id: id}, nil
+	}
+	ok, err := actsFor(ctx, id, auction.Seller, auction.AssetID, RightTransfer)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("only the seller may manage auction %s", auction.ID)
+	}
+	return &actor{id: id, operator: true}, nil
+}
+
+// emitAuctionOperation is emitOperation for a step of auction, which may no
+// longer reflect the current owner of the auctioned asset.
+func emitAuctionOperation(ctx contractapi.TransactionContextInterface, function string, auction *Auction, a *actor) error {
+	return emitOperation(ctx, function, &Asset{ID: auction.AssetID}, auction.Seller, a)
+}
+
+func readAuction(ctx contractapi.TransactionContextInterface, auctionID string) (*Auction, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(auctionObjectType, []string{auctionID})
+	if err != nil {
+		return nil, fmt.Errorf("create auction key: %w", err)
+	}
+	b, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("get state: %w", err)
+	}
+	if b == nil {
+		return nil, nil
+	}
+	var auction Auction
+	if err := json.Unmarshal(b, &auction); err != nil {
+		return nil, fmt.Errorf("unmarshal auction: %w", err)
+	}
+	return &auction, nil
+}
+
+func putAuction(ctx contractapi.TransactionContextInterface, auction *Auction) error {
+	key, err := ctx.GetStub().CreateCompositeKey(auctionObjectType, []string{auction.ID})
+	if err != nil {
+		return fmt.Errorf("create auction key: %w", err)
+	}
+	b, err := json.Marshal(auction)
+	if err != nil {
+		return fmt.Errorf("marshal auction: %w", err)
+	}
+	return ctx.GetStub().PutState(key, b)
+}
+
+func transientBid(ctx contractapi.TransactionContextInterface) ([]byte, error) {
+	transient, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return nil, fmt.Errorf("get transient: %w", err)
+	}
+	raw, ok := transient[bidTransientKey]
+	if !ok || len(raw) == 0 {
+		return nil, fmt.Errorf("bid must be passed as transient data under %q", bidTransientKey)
+	}
+	return raw, nil
+}
+
+// parseBid decodes a transient bid and checks its price against the auction
+// currency.
+func parseBid(raw []byte, currency string) (Money, error) {
+	var b bid
+	if err := json.Unmarshal(raw, &b); err != nil {
+		return Money{}, fmt.Errorf("unmarshal bid: %w", err)
+	}
+	if strings.TrimSpace(b.Salt) == "" {
+		return Money{}, errors.New("bid salt is required")
+	}
+	price, err := parseValue("bid price", b.Price)
+	if err != nil {
+		return Money{}, err
+	}
+	if price.Currency != currency {
+		return Money{}, fmt.Errorf("bid price must be in %s", currency)
+	}
+	return price, nil
+}
+
+// implicitCollection returns the implicit private data collection of the
+// invoker's org.
+func implicitCollection(ctx contractapi.TransactionContextInterface) (string, error) {
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("get msp id: %w", err)
+	}
+	return "_implicit_org_" + mspID, nil
+}
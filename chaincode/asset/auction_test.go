@@ -0,0 +1,163 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAuction(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("painting", alice.id(), "1000 EUR")
+	e.verifyOwners(bob.id(), carol.id())
+
+	wantErr(t, e.assets.CreateAuction(e.tx(bob), "auc1", "painting", "EUR"), "only the owner of asset painting")
+	wantErr(t, e.assets.CreateAuction(e.tx(alice), "auc1", "painting", "ZZZ"), "unsupported currency")
+	mustOK(t, e.assets.CreateAuction(e.tx(alice), "auc1", "painting", "eur"))
+	wantErr(t, e.assets.CreateAuction(e.tx(alice), "auc1", "painting", "EUR"), "auction auc1 already exists")
+
+	bobBid := `{"price":"1500 EUR","salt":"s1"}`
+	carolBid := `{"price":"1200 EUR","salt":"s2"}`
+
+	_, err := e.assets.SubmitBid(e.tx(alice).withTransient(bidTransientKey, bobBid), "auc1")
+	wantErr(t, err, "seller cannot bid")
+	_, err = e.assets.SubmitBid(e.tx(bob), "auc1")
+	wantErr(t, err, "transient data")
+	_, err = e.assets.SubmitBid(e.tx(bob).withTransient(bidTransientKey, `{"price":"1 USD","salt":"x"}`), "auc1")
+	wantErr(t, err, "bid price must be in EUR")
+
+	bobTx, err := e.assets.SubmitBid(e.tx(bob).withTransient(bidTransientKey, bobBid), "auc1")
+	mustOK(t, err)
+	carolTx, err := e.assets.SubmitBid(e.tx(carol).withTransient(bidTransientKey, carolBid), "auc1")
+	mustOK(t, err)
+
+	auction, err := e.assets.GetAuction(e.tx(carol), "auc1")
+	mustOK(t, err)
+	if len(auction.Bids) != 2 || auction.Bids[0].Collection != "_implicit_org_Org2MSP" {
+		t.Fatalf("unexpected bids %+v", auction.Bids)
+	}
+
+	wantErr(t, e.assets.RevealBid(e.tx(bob).withTransient(bidTransientKey, bobBid), "auc1", bobTx), "bids can only be revealed while auction auc1 is closed")
+	wantErr(t, e.assets.CloseAuction(e.tx(bob), "auc1"), "only the seller may manage auction auc1")
+	mustOK(t, e.assets.CloseAuction(e.tx(alice), "auc1"))
+	_, err = e.assets.SubmitBid(e.tx(bob).withTransient(bidTransientKey, bobBid), "auc1")
+	wantErr(t, err, "no longer accepts bids")
+
+	wantErr(t, e.assets.RevealBid(e.tx(carol).withTransient(bidTransientKey, bobBid), "auc1", bobTx), "only the bidder may reveal a bid")
+	wantErr(t, e.assets.RevealBid(e.tx(bob).withTransient(bidTransientKey, `{"price":"9999 EUR","salt":"s1"}`), "auc1", bobTx), "does not match sealed bid")
+	wantErr(t, e.assets.RevealBid(e.tx(bob).withTransient(bidTransientKey, bobBid), "auc1", "nope"), "has no bid nope")
+	mustOK(t, e.assets.RevealBid(e.tx(bob).withTransient(bidTransientKey, bobBid), "auc1", bobTx))
+	wantErr(t, e.assets.RevealBid(e.tx(bob).withTransient(bidTransientKey, bobBid), "auc1", bobTx), "already revealed")
+	mustOK(t, e.assets.RevealBid(e.tx(carol).withTransient(bidTransientKey, carolBid), "auc1", carolTx))
+
+	mustOK(t, e.assets.EndAuction(e.tx(alice), "auc1"))
+	auction, err = e.assets.GetAuction(e.tx(carol), "auc1")
+	mustOK(t, err)
+	if auction.Status != auctionEnded || auction.Winner != bob.id() || auction.Price.Amount != 150000 {
+		t.Fatalf("unexpected result %+v", auction)
+	}
+	if owner := e.readAsset("painting").Owner; owner != bob.id() {
+		t.Fatalf("owner = %s, want the winner", owner)
+	}
+	wantErr(t, e.assets.EndAuction(e.tx(alice), "auc1"), "is ended, not closed")
+
+	// Every step is audited on the asset; the transfer records the end.
+	trail, err := e.assets.GetAuditTrail(e.tx(carol), "painting", 20, "")
+	mustOK(t, err)
+	var functions []string
+	for _, rec := range trail.Records {
+		functions = append(functions, rec.Function)
+	}
+	if got := strings.Join(functions, ","); got != "CreateAsset,CreateAuction,SubmitBid,SubmitBid,CloseAuction,RevealBid,RevealBid,EndAuction" {
+		t.Fatalf("trail = %s", got)
+	}
+	if c := trail.Records[5].Changes; len(c) != 1 || c[0].Field != "auction" || strings.Contains(c[0].Before, `"revealed":[{`) || !strings.Contains(c[0].After, `"revealed":[{`) {
+		t.Fatalf("reveal changes %+v", c)
+	}
+
+	_, err = e.assets.GetAuction(e.tx(carol), "missing")
+	wantErr(t, err, "auction missing not found")
+}
+
+func TestEndAuctionWithoutBids(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("vase", alice.id(), "10 EUR")
+	mustOK(t, e.assets.CreateAuction(e.tx(alice), "auc1", "vase", "EUR"))
+	mustOK(t, e.assets.CloseAuction(e.tx(alice), "auc1"))
+	mustOK(t, e.assets.EndAuction(e.tx(alice), "auc1"))
+
+	if owner := e.readAsset("vase").Owner; owner != alice.id() {
+		t.Fatalf("owner = %s", owner)
+	}
+}
+
+func TestEndAuctionSettlement(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("clock", alice.id(), "200 EUR")
+	e.verifyOwners(bob.id())
+	mustOK(t, e.assets.CreateAuction(e.tx(alice), "auc1", "clock", "EUR"))
+
+	// carol is not KYC verified and cannot receive the asset.
+	bids := map[*mockIdentity]string{bob: `{"price":"300 EUR","salt":"s1"}`, carol: `{"price":"400 EUR","salt":"s2"}`}
+	txs := map[*mockIdentity]string{}
+	for _, bidder := range []*mockIdentity{bob, carol} {
+		txID, err := e.assets.SubmitBid(e.tx(bidder).withTransient(bidTransientKey, bids[bidder]), "auc1")
+		mustOK(t, err)
+		txs[bidder] = txID
+	}
+	mustOK(t, e.assets.CloseAuction(e.tx(alice), "auc1"))
+	for _, bidder := range []*mockIdentity{bob, carol} {
+		mustOK(t, e.assets.RevealBid(e.tx(bidder).withTransient(bidTransientKey, bids[bidder]), "auc1", txs[bidder]))
+	}
+
+	mustOK(t, e.assets.SetConfig(e.tx(org3Admin), ContractConfig{AllowedOwnerMSPs: []string{"Org2MSP"}}))
+	wantErr(t, e.assets.EndAuction(e.tx(alice), "auc1"), "Org1MSP identities may not own or manage assets")
+	mustOK(t, e.assets.SetConfig(e.tx(org3Admin), ContractConfig{}))
+
+	// A frozen asset keeps the auction closed until it is retried or
+	// cancelled.
+	mustOK(t, e.assets.FreezeAsset(e.tx(regulator), "clock", "CASE-1"))
+	wantErr(t, e.assets.EndAuction(e.tx(alice), "auc1"), "asset clock is frozen")
+	mustOK(t, e.assets.UnfreezeAsset(e.tx(regulator), "clock"))
+
+	mustOK(t, e.assets.EndAuction(e.tx(alice), "auc1"))
+	auction, err := e.assets.GetAuction(e.tx(carol), "auc1")
+	mustOK(t, err)
+	if auction.Winner != bob.id() || auction.Price.Amount != 30000 || len(auction.PassedOver) != 1 || auction.PassedOver[0] != carol.id() {
+		t.Fatalf("unexpected result %+v", auction)
+	}
+	if owner := e.readAsset("clock").Owner; owner != bob.id() {
+		t.Fatalf("owner = %s, want bob", owner)
+	}
+}
+
+func TestCancelAuction(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("lamp", alice.id(), "50 EUR")
+	mustOK(t, e.assets.CreateAuction(e.tx(alice), "auc1", "lamp", "EUR"))
+	mustOK(t, e.assets.CloseAuction(e.tx(alice), "auc1"))
+
+	wantErr(t, e.assets.CancelAuction(e.tx(bob), "auc1"), "only the seller may manage auction auc1")
+	mustOK(t, e.assets.CancelAuction(e.tx(alice), "auc1"))
+	wantErr(t, e.assets.CancelAuction(e.tx(alice), "auc1"), "auction auc1 is cancelled and can no longer be cancelled")
+	wantErr(t, e.assets.EndAuction(e.tx(alice), "auc1"), "is cancelled, not closed")
+
+	trail, err := e.assets.GetAuditTrail(e.tx(carol), "lamp", 10, "")
+	mustOK(t, err)
+	if last := trail.Records[len(trail.Records)-1]; last.Function != "CancelAuction" || !strings.Contains(last.Changes[0].After, `"status":"cancelled"`) {
+		t.Fatalf("last audit record %+v", last)
+	}
+}
+
+func TestCreateAuctionChecksAsset(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("lamp", alice.id(), "50 EUR")
+
+	mustOK(t, e.assets.FreezeAsset(e.tx(regulator), "lamp", "CASE-2"))
+	wantErr(t, e.assets.CreateAuction(e.tx(alice), "auc1", "lamp", "EUR"), "asset lamp is frozen")
+	mustOK(t, e.assets.UnfreezeAsset(e.tx(regulator), "lamp"))
+
+	mustOK(t, e.assets.OpenDispute(e.tx(alice), "lamp", "contested"))
+	wantErr(t, e.assets.CreateAuction(e.tx(alice), "auc1", "lamp", "EUR"), "asset lamp is disputed")
+	mustOK(t, e.assets.ResolveDispute(e.tx(regulator), "lamp", "dismissed", ""))
+	mustOK(t, e.assets.CreateAuction(e.tx(alice), "auc1", "lamp", "EUR"))
+}
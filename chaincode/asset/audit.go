@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	auditObjectType = "audit"
+	// auditReasonKey is the transient data key an invoker may use to attach
+	// a free-form reason to a mutation.
+	auditReasonKey = "reason"
+)
+
+type AuditRecord struct {
+	AssetID   string         `json:"assetId"`
+	TxID      string         `json:"txId"`
+	Timestamp string         `json:"timestamp"`
+	Function  string         `json:"function"`
+	MSPID     string         `json:"mspId"`
+	Subject   string         `json:"subject"`
+	Reason    string         `json:"reason,omitempty" metadata:",optional"`
+	Changes   []*FieldChange `json:"changes,omitempty" metadata:",optional"`
+}
+
+// FieldChange holds the JSON encoded value of a single asset field, or of a
+// record attached to the asset such as its lease, before and after a
+// mutation. Before is empty for creates and After is empty for deletes.
+type FieldChange struct {
+	Field  string `json:"field"`
+	Before string `json:"before,omitempty" metadata:",optional"`
+	After  string `json:"after,omitempty" metadata:",optional"`
+}
+
+type AuditTrailPage struct {
+	Records  []*AuditRecord `json:"records"`
+	Bookmark string         `json:"bookmark"`
+	Count    int32          `json:"count"`
+}
+
+// GetAuditTrail pages through the audit records of an asset, oldest first.
+func (c *AssetContract) GetAuditTrail(ctx contractapi.TransactionContextInterface, id string, pageSize int32, bookmark string) (*AuditTrailPage, error) {
+	if pageSize <= 0 {
+		return nil, errors.New("pageSize must be > 0")
+	}
+	id, err := existingID("id", id)
+	if err != nil {
+		return nil, err
+	}
+
+	iter, meta, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(auditObjectType, []string{id}, pageSize, bookmark)
+	if err != nil {
+		return nil, fmt.Errorf("audit query: %w", err)
+	}
+	defer iter.Close()
+
+	page := &AuditTrailPage{Records: []*AuditRecord{}}
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return nil, fmt.Errorf("iter next: %w", err)
+		}
+		var rec AuditRecord
+		if err := json.Unmarshal(kv.Value, &rec); err != nil {
+			return nil, fmt.Errorf("unmarshal audit record: %w", err)
+		}
+		page.Records = append(page.Records, &rec)
+	}
+	page.Bookmark = meta.GetBookmark()
+	page.Count = meta.GetFetchedRecordsCount()
+	return page, nil
+}
+
+// recordAudit appends an immutable audit record for a mutation of an asset.
+// before is nil for creates and after is nil for deletes.
+func recordAudit(ctx contractapi.TransactionContextInterface, function string, before, after *Asset) error {
+	var id string
+	if after != nil {
+		id = after.ID
+	} else {
+		id = before.ID
+	}
+	changes, err := diffAssets(before, after)
+	if err != nil {
+		return err
+	}
+	return putAuditRecord(ctx, function, id, changes)
+}
+
+// recordRelatedAudit appends the audit record for a mutation of a record
+// attached to an asset, such as a freeze or a lease, which is reported as a
+// change of field. before is nil when the record is created and after is nil
+// when it is removed.
+func recordRelatedAudit(ctx contractapi.TransactionContextInterface, function string, assetID string, field string, before, after interface{}) error {
+	b, err := auditJSON(before)
+	if err != nil {
+		return err
+	}
+	a, err := auditJSON(after)
+	if err != nil {
+		return err
+	}
+	var changes []*FieldChange
+	if b != a {
+		changes = []*FieldChange{{Field: field, Before: b, After: a}}
+	}
+	return putAuditRecord(ctx, function, assetID, changes)
+}
+
+// auditJSON encodes v for a FieldChange. nil, including nil pointers, is
+// empty.
+func auditJSON(v interface{}) (string, error) {
+	if v == nil {
+		return "", nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("marshal audit value: %w", err)
+	}
+	if string(b) == "null" {
+		return "", nil
+	}
+	return string(b), nil
+}
+
+func putAuditRecord(ctx contractapi.TransactionContextInterface, function string, id string, changes []*FieldChange) error {
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Keys sort by transaction time, so the trail pages in order.
+	stub := ctx.GetStub()
+	key, err := stub.CreateCompositeKey(auditObjectType, []string{id, now.Format(indexTimeLayout), stub.GetTxID()})
+	if err != nil {
+		return fmt.Errorf("create audit key: %w", err)
+	}
+
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("get msp id: %w", err)
+	}
+	cert, err := ctx.GetClientIdentity().GetX509Certificate()
+	if err != nil {
+		return fmt.Errorf("get client certificate: %w", err)
+	}
+	var subject string
+	if cert != nil {
+		subject = cert.Subject.String()
+	}
+
+	transient, err := stub.GetTransient()
+	if err != nil {
+		return fmt.Errorf("get transient: %w", err)
+	}
+
+	rec := AuditRecord{
+		AssetID:   id,
+		TxID:      stub.GetTxID(),
+		Timestamp: now.Format(time.RFC3339Nano),
+		Function:  function,
+		MSPID:     mspID,
+		Subject:   subject,
+		Reason:    string(transient[auditReasonKey]),
+		Changes:   changes,
+	}
+
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("marshal audit record: %w", err)
+	}
+	return stub.PutState(key, b)
+}
+
+// diffAssets compares the JSON representation of two versions of an asset
+// field by field. Fields are reported in alphabetical order.
+func diffAssets(before, after *Asset) ([]*FieldChange, error) {
+	beforeFields, err := assetFields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := assetFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]struct{})
+	for name := range beforeFields {
+		names[name] = struct{}{}
+	}
+	for name := range afterFields {
+		names[name] = struct{}{}
+	}
+
+	var changes []*FieldChange
+	for name := range names {
+		b, a := string(beforeFields[name]), string(afterFields[name])
+		if b != a {
+			changes = append(changes, &FieldChange{Field: name, Before: b, After: a})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes, nil
+}
+
+func assetFields(asset *Asset) (map[string]json.RawMessage, error) {
+	if asset == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(asset)
+	if err != nil {
+		return nil, fmt.Errorf("marshal asset: %w", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, fmt.Errorf("unmarshal asset: %w", err)
+	}
+	return fields, nil
+}
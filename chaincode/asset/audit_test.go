@@ -0,0 +1,87 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetAuditTrail(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("asset1", "Alice", "10 EUR")
+	mustOK(t, e.assets.UpdateAssetValue(e.tx(alice).withTransient(auditReasonKey, "reappraisal"), "asset1", "20 EUR"))
+	mustOK(t, e.assets.DeleteAsset(e.tx(alice), "asset1"))
+
+	first, err := e.assets.GetAuditTrail(e.tx(bob), "asset1", 2, "")
+	mustOK(t, err)
+	if first.Count != 2 || first.Bookmark == "" {
+		t.Fatalf("first page = %d records, bookmark %q", first.Count, first.Bookmark)
+	}
+	second, err := e.assets.GetAuditTrail(e.tx(bob), "asset1", 2, first.Bookmark)
+	mustOK(t, err)
+	if second.Count != 1 || second.Bookmark != "" {
+		t.Fatalf("second page = %d records, bookmark %q", second.Count, second.Bookmark)
+	}
+
+	byFunction := make(map[string]*AuditRecord)
+	for _, rec := range append(first.Records, second.Records...) {
+		byFunction[rec.Function] = rec
+		if rec.MSPID != "Org1MSP" || rec.Subject == "" {
+			t.Errorf("%s: unexpected invoker %s %s", rec.Function, rec.MSPID, rec.Subject)
+		}
+	}
+
+	update := byFunction["UpdateAssetValue"]
+	if update == nil || update.Reason != "reappraisal" {
+		t.Fatalf("unexpected update record %+v", update)
+	}
+	changed := make(map[string]*FieldChange)
+	for _, c := range update.Changes {
+		changed[c.Field] = c
+	}
+	if c := changed["value"]; c == nil || c.Before != `{"amount":1000,"currency":"EUR","decimals":2}` || c.After != `{"amount":2000,"currency":"EUR","decimals":2}` {
+		t.Fatalf("unexpected value change %+v", c)
+	}
+	if _, ok := changed["owner"]; ok {
+		t.Fatal("unchanged owner reported")
+	}
+
+	del := byFunction["DeleteAsset"]
+	for _, c := range del.Changes {
+		if c.After != "" {
+			t.Fatalf("delete reports after value for %s", c.Field)
+		}
+	}
+
+	_, err = e.assets.GetAuditTrail(e.tx(bob), "asset1", 0, "")
+	wantErr(t, err, "pageSize must be > 0")
+}
+
+func TestAuditTrailOrderAndRelatedRecords(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("asset1", "Alice", "10 EUR")
+	mustOK(t, e.assets.FreezeAsset(e.tx(regulator), "asset1", "CASE-1"))
+	mustOK(t, e.assets.UnfreezeAsset(e.tx(regulator), "asset1"))
+	mustOK(t, e.assets.UpdateAssetValue(e.tx(alice), "asset1", "20 EUR"))
+
+	page, err := e.assets.GetAuditTrail(e.tx(bob), "asset1", 10, "")
+	mustOK(t, err)
+	var functions []string
+	for _, rec := range page.Records {
+		functions = append(functions, rec.Function)
+	}
+	// Transaction IDs are hashes, so only time ordered keys keep this order.
+	if got := strings.Join(functions, ","); got != "CreateAsset,FreezeAsset,UnfreezeAsset,UpdateAssetValue" {
+		t.Fatalf("trail = %s", got)
+	}
+
+	freeze, unfreeze := page.Records[1].Changes, page.Records[2].Changes
+	if len(freeze) != 1 || freeze[0].Field != "freeze" || freeze[0].Before != "" || !strings.Contains(freeze[0].After, `"caseRef":"CASE-1"`) {
+		t.Fatalf("freeze changes %+v", freeze)
+	}
+	if len(unfreeze) != 1 || unfreeze[0].Before != freeze[0].After || unfreeze[0].After != "" {
+		t.Fatalf("unfreeze changes %+v", unfreeze)
+	}
+
+	_, err = e.assets.GetAuditTrail(e.tx(bob), " ", 10, "")
+	wantErr(t, err, "id is required")
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	commitmentObjectType = "commitment"
+	// openingTransientKey carries {"value": "125.50 EUR", "salt": "..."}.
+	openingTransientKey = "opening"
+	// minSaltBytes is the minimum length of a commitment salt. Shorter salts
+	// let anyone recover the value by trying every amount and salt.
+	minSaltBytes = 16
+)
+
+// opening is the concealed value of an asset and the salt of its commitment.
+type opening struct {
+	Value string `json:"value"`
+	Salt  string `json:"salt"`
+}
+
+// CreateAssetCommitted creates an asset whose public record only carries a
+// commitment to its value. The value and salt are passed as transient data
+// and kept in the implicit collection of the invoker's org.
+func (c *AssetContract) CreateAssetCommitted(ctx contractapi.TransactionContextInterface, id string, owner string) error {
+	guard, err := beginRequest(ctx, "CreateAssetCommitted", id, owner)
+	if err != nil {
+		return err
+	}
+	if _, ok := guard.replayed(); ok {
+		return nil
+	}
+
+	cfg, err := readConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if id, err = cfg.normalizeID("id", id); err != nil {
+		return err
+	}
+	if owner, err = cfg.normalizeOwner("owner", owner); err != nil {
+		return err
+	}
+	if err := requireVerifiedOwner(ctx, owner); err != nil {
+		return err
+	}
+	o, err := transientOpening(ctx)
+	if err != nil {
+		return err
+	}
+
+	asset, err := c.newAsset(ctx, id, owner, o.Value)
+	if err != nil {
+		return err
+	}
+	// Valuations outlive deleted assets and would apply to this one.
+	if err := checkNotAppraised(ctx, asset.ID); err != nil {
+		return err
+	}
+	if err := conceal(ctx, asset, o); err != nil {
+		return err
+	}
+	if err := c.saveAsset(ctx, "CreateAssetCommitted", nil, asset); err != nil {
+		return err
+	}
+	return guard.complete(ctx, id)
+}
+
+// CommitAssetValue replaces the value of an asset, public or concealed, with
+// a commitment to the transient value and salt.
+func (c *AssetContract) CommitAssetValue(ctx contractapi.TransactionContextInterface, id string) error {
+	guard, err := beginRequest(ctx, "CommitAssetValue", id)
+	if err != nil {
+		return err
+	}
+	if _, ok := guard.replayed(); ok {
+		return nil
+	}
+
+	cfg, err := readConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if err := cfg.checkOwnerMSP(ctx); err != nil {
+		return err
+	}
+	o, err := transientOpening(ctx)
+	if err != nil {
+		return err
+	}
+	value, err := parseValue("value", o.Value)
+	if err != nil {
+		return err
+	}
+	if err := cfg.checkValue("value", value); err != nil {
+		return err
+	}
+
+	asset, err := c.ReadAsset(ctx, id)
+	if err != nil {
+		return err
+	}
+	a, err := authorize(ctx, asset, RightUpdateValue)
+	if err != nil {
+		return err
+	}
+	if err := checkNotShared(asset); err != nil {
+		return err
+	}
+	if err := checkNotFrozen(ctx, asset.ID); err != nil {
+		return err
+	}
+	if err := checkNotAppraised(ctx, asset.ID); err != nil {
+		return err
+	}
+
+	now, err := txTimeRFC3339(ctx)
+	if err != nil {
+		return err
+	}
+
+	before := *asset
+	if err := conceal(ctx, asset, o); err != nil {
+		return err
+	}
+	asset.UpdatedAt = now
+	asset.Version++
+
+	if err := c.saveAsset(ctx, "CommitAssetValue", &before, asset); err != nil {
+		return err
+	}
+	if err := emitOperation(ctx, "CommitAssetValue", asset, asset.Owner, a); err != nil {
+		return err
+	}
+	return guard.complete(ctx, "")
+}
+
+// RevealValue discloses the concealed value of an asset. The value and salt
+// must match the commitment and are published on the asset, so anyone can
+// recompute the commitment from them.
+func (c *AssetContract) RevealValue(ctx contractapi.TransactionContextInterface, id string, value string, salt string) error {
+	guard, err := beginRequest(ctx, "RevealValue", id, value, salt)
+	if err != nil {
+		return err
+	}
+	if _, ok := guard.replayed(); ok {
+		return nil
+	}
+
+	if err := requireOwnerMSP(ctx); err != nil {
+		return err
+	}
+
+	asset, err := c.ReadAsset(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !concealed(asset) {
+		return fmt.Errorf("asset %s has no concealed value", asset.ID)
+	}
+	a, err := authorize(ctx, asset, RightUpdateValue)
+	if err != nil {
+		return err
+	}
+	if err := checkNotFrozen(ctx, asset.ID); err != nil {
+		return err
+	}
+	if err := checkNotAppraised(ctx, asset.ID); err != nil {
+		return err
+	}
+	revealed, err := parseValue("value", value)
+	if err != nil {
+		return err
+	}
+	if valueCommitment(revealed, salt) != asset.ValueCommitment {
+		return fmt.Errorf("value and salt do not match the commitment of asset %s", asset.ID)
+	}
+
+	now, err := txTimeRFC3339(ctx)
+	if err != nil {
+		return err
+	}
+
+	before := *asset
+	asset.Value = revealed
+	asset.ValueSalt = salt
+	asset.UpdatedAt = now
+	asset.Version++
+
+	if err := c.saveAsset(ctx, "RevealValue", &before, asset); err != nil {
+		return err
+	}
+	if err := emitOperation(ctx, "RevealValue", asset, asset.Owner, a); err != nil {
+		return err
+	}
+	return guard.complete(ctx, "")
+}
+
+// VerifyValueCommitment reports whether value and salt open the commitment
+// of an asset, letting auditors check a value disclosed to them off-chain.
+func (c *AssetContract) VerifyValueCommitment(ctx contractapi.TransactionContextInterface, id string, value string, salt string) (bool, error) {
+	asset, err := c.ReadAsset(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	if asset.ValueCommitment == "" {
+		return false, fmt.Errorf("asset %s has no value commitment", asset.ID)
+	}
+	m, err := ParseMoney(value)
+	if err != nil {
+		return false, err
+	}
+	return valueCommitment(m, salt) == asset.ValueCommitment, nil
+}
+
+// concealed reports whether the value of asset is committed but not revealed.
+func concealed(asset *Asset) bool {
+	return asset.ValueCommitment != "" && asset.ValueSalt == ""
+}
+
+// valueCommitment returns the hex sha256 of the canonical value, as printed
+// by Money.String, followed by the salt.
+func valueCommitment(value Money, salt string) string {
+	sum := sha256.Sum256([]byte(value.String() + salt))
+	return hex.EncodeToString(sum[:])
+}
+
+// conceal stores the opening in the invoker's implicit collection and
+// replaces the value of asset with the commitment.
+func conceal(ctx contractapi.TransactionContextInterface, asset *Asset, o *opening) error {
+	value, err := parseValue("value", o.Value)
+	if err != nil {
+		return err
+	}
+	collection, err := implicitCollection(ctx)
+	if err != nil {
+		return err
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(commitmentObjectType, []string{asset.ID})
+	if err != nil {
+		return fmt.Errorf("create commitment key: %w", err)
+	}
+	b, err := json.Marshal(opening{Value: value.String(), Salt: o.Salt})
+	if err != nil {
+		return fmt.Errorf("marshal opening: %w", err)
+	}
+	if err := ctx.GetStub().PutPrivateData(collection, key, b); err != nil {
+		return fmt.Errorf("put private data: %w", err)
+	}
+
+	asset.Value = Money{}
+	asset.ValuationID = ""
+	asset.ValueCommitment = valueCommitment(value, o.Salt)
+	asset.ValueSalt = ""
+	asset.ValueCollection = collection
+	return nil
+}
+
+func transientOpening(ctx contractapi.TransactionContextInterface) (*opening, error) {
+	transient, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return nil, fmt.Errorf("get transient: %w", err)
+	}
+	raw, ok := transient[openingTransientKey]
+	if !ok || len(raw) == 0 {
+		return nil, fmt.Errorf("value and salt must be passed as transient data under %q", openingTransientKey)
+	}
+	var o opening
+	if err := json.Unmarshal(raw, &o); err != nil {
+		return nil, fmt.Errorf("unmarshal opening: %w", err)
+	}
+	if strings.TrimSpace(o.Salt) == "" {
+		return nil, errors.New("salt is required")
+	}
+	if b, err := hex.DecodeString(o.Salt); err != nil || len(b) < minSaltBytes {
+		return nil, fmt.Errorf("salt must be at least %d random bytes in hex", minSaltBytes)
+	}
+	return &o, nil
+}
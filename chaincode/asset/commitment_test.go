@@ -0,0 +1,95 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+var (
+	pepper = strings.Repeat("5e", 16)
+	salt   = strings.Repeat("a1", 16)
+)
+
+func TestCreateAssetCommitted(t *testing.T) {
+	e := newTestEnv(t)
+	e.verifyOwners("Alice")
+	opening := `{"value":"125.5 EUR","salt":"` + pepper + `"}`
+
+	wantErr(t, e.assets.CreateAssetCommitted(e.tx(alice), "secret", "Alice"), "transient data")
+	wantErr(t, e.assets.CreateAssetCommitted(e.tx(alice).withTransient(openingTransientKey, `{"value":"1 EUR"}`), "secret", "Alice"), "salt is required")
+	wantErr(t, e.assets.CreateAssetCommitted(e.tx(alice).withTransient(openingTransientKey, `{"value":"1 EUR","salt":"pepper"}`), "secret", "Alice"), "salt must be at least 16 random bytes in hex")
+	wantErr(t, e.assets.CreateAssetCommitted(e.tx(alice).withTransient(openingTransientKey, `{"value":"1 EUR","salt":"`+strings.Repeat("5e", 15)+`"}`), "secret", "Alice"), "salt must be at least 16 random bytes in hex")
+	mustOK(t, e.assets.CreateAssetCommitted(e.tx(alice).withTransient(openingTransientKey, opening), "secret", "Alice"))
+
+	asset := e.readAsset("secret")
+	if asset.Value != (Money{}) || asset.ValueCommitment != valueCommitment(Money{Amount: 12550, Currency: "EUR", Decimals: 2}, pepper) {
+		t.Fatalf("value not concealed: %+v", asset)
+	}
+	if asset.ValueCollection != "_implicit_org_Org1MSP" {
+		t.Fatalf("collection = %s", asset.ValueCollection)
+	}
+	key, _ := e.tx(alice).stub.CreateCompositeKey(commitmentObjectType, []string{"secret"})
+	if _, ok := e.ledger.private[asset.ValueCollection][key]; !ok {
+		t.Fatalf("opening not stored privately: %v", e.ledger.private[asset.ValueCollection])
+	}
+
+	// Concealed values stay out of the value index.
+	page, err := e.assets.GetAssetsByValueRange(e.tx(bob), "0 EUR", "1000 EUR", 10, "")
+	mustOK(t, err)
+	if len(page.Assets) != 0 {
+		t.Fatalf("concealed asset indexed: %v", assetIDs(page))
+	}
+	wantErr(t, e.assets.UpdateAssetValue(e.tx(alice), "secret", "1 EUR"), "has a concealed value")
+
+	ok, err := e.assets.VerifyValueCommitment(e.tx(bob), "secret", "125.50 EUR", pepper)
+	mustOK(t, err)
+	if !ok {
+		t.Fatal("commitment does not verify")
+	}
+	ok, err = e.assets.VerifyValueCommitment(e.tx(bob), "secret", "125.51 EUR", pepper)
+	mustOK(t, err)
+	if ok {
+		t.Fatal("wrong value verifies")
+	}
+
+	wantErr(t, e.assets.RevealValue(e.tx(alice), "secret", "125.50 EUR", salt), "do not match the commitment")
+	mustOK(t, e.assets.FreezeAsset(e.tx(regulator), "secret", "CASE-1"))
+	wantErr(t, e.assets.RevealValue(e.tx(alice), "secret", "125.50 EUR", pepper), "is frozen")
+	mustOK(t, e.assets.UnfreezeAsset(e.tx(regulator), "secret"))
+	mustOK(t, e.assets.RevealValue(e.tx(alice), "secret", "125.50 EUR", pepper))
+	asset = e.readAsset("secret")
+	if asset.Value.Amount != 12550 || asset.ValueSalt != pepper {
+		t.Fatalf("not revealed: %+v", asset)
+	}
+	wantErr(t, e.assets.RevealValue(e.tx(alice), "secret", "125.50 EUR", pepper), "has no concealed value")
+
+	// Revealed values are indexed and can be updated publicly again.
+	page, err = e.assets.GetAssetsByValueRange(e.tx(bob), "0 EUR", "1000 EUR", 10, "")
+	mustOK(t, err)
+	if !equalIDs(assetIDs(page), []string{"secret"}) {
+		t.Fatalf("page = %v", assetIDs(page))
+	}
+	mustOK(t, e.assets.UpdateAssetValue(e.tx(alice), "secret", "1 EUR"))
+	if asset = e.readAsset("secret"); asset.ValueCommitment != "" {
+		t.Fatal("commitment kept after public update")
+	}
+}
+
+func TestCommitAssetValue(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("public", alice.id(), "10 EUR")
+
+	wantErr(t, e.assets.CommitAssetValue(e.tx(bob).withTransient(openingTransientKey, `{"value":"5 EUR","salt":"`+salt+`"}`), "public"), "updateValue right")
+	wantErr(t, e.assets.CommitAssetValue(e.tx(alice).withTransient(openingTransientKey, `{"value":"5","salt":"`+salt+`"}`), "public"), "must be an amount followed by a currency")
+	mustOK(t, e.assets.CommitAssetValue(e.tx(alice).withTransient(openingTransientKey, `{"value":"5 EUR","salt":"`+salt+`"}`), "public"))
+
+	asset := e.readAsset("public")
+	if !concealed(asset) || asset.Version != 2 {
+		t.Fatalf("not concealed: %+v", asset)
+	}
+	_, err := e.assets.VerifyValueCommitment(e.tx(bob), "missing", "5 EUR", salt)
+	wantErr(t, err, "not found")
+	e.createAsset("plain", "Alice", "1 EUR")
+	_, err = e.assets.VerifyValueCommitment(e.tx(bob), "plain", "1 EUR", salt)
+	wantErr(t, err, "has no value commitment")
+}
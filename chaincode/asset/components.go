@@ -0,0 +1,201 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+type AssetTree struct {
+	Asset      *Asset       `json:"asset"`
+	Components []*AssetTree `json:"components,omitempty" metadata:",optional"`
+}
+
+func (c *AssetContract) AttachComponent(ctx contractapi.TransactionContextInterface, parentID string, childID string) error {
+	guard, err := beginRequest(ctx, "AttachComponent", parentID, childID)
+	if err != nil {
+		return err
+	}
+	if _, ok := guard.replayed(); ok {
+		return nil
+	}
+
+	if err := requireOwnerMSP(ctx); err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(parentID) == strings.TrimSpace(childID) {
+		return errors.New("an asset cannot be a component of itself")
+	}
+
+	parent, err := c.ReadAsset(ctx, parentID)
+	if err != nil {
+		return err
+	}
+	child, err := c.ReadAsset(ctx, childID)
+	if err != nil {
+		return err
+	}
+	a, err := authorize(ctx, child, RightTransfer)
+	if err != nil {
+		return err
+	}
+	// The child changes owner with the parent from now on, so the invoker
+	// must be allowed to transfer both.
+	if _, err := authorize(ctx, parent, RightTransfer); err != nil {
+		return err
+	}
+	if err := checkNotFrozen(ctx, child.ID); err != nil {
+		return err
+	}
+	// A disputed, shared or leased asset would otherwise change owner with
+	// its new parent, bypassing the checks of a direct transfer.
+	if err := checkNoOpenDispute(ctx, child.ID); err != nil {
+		return err
+	}
+	if len(child.Shares) > 0 {
+		return fmt.Errorf("asset %s is held in shares and cannot become a component", child.ID)
+	}
+	lease, err := activeLease(ctx, child.ID)
+	if err != nil {
+		return err
+	}
+	if lease != nil {
+		return fmt.Errorf("asset %s is leased until %s and cannot become a component", child.ID, lease.End)
+	}
+	if child.ParentID != "" {
+		return fmt.Errorf("asset %s is already a component of %s", child.ID, child.ParentID)
+	}
+	if child.Owner != parent.Owner {
+		return fmt.Errorf("asset %s is owned by %s, but parent %s is owned by %s", child.ID, child.Owner, parent.ID, parent.Owner)
+	}
+
+	// Attaching would create a cycle if the child is an ancestor of the parent.
+	for ancestor := parent; ancestor.ParentID != ""; {
+		if ancestor.ParentID == child.ID {
+			return fmt.Errorf("attaching %s to %s would create a cycle", child.ID, parent.ID)
+		}
+		ancestor, err = readAsset(ctx, ancestor.ParentID)
+		if err != nil {
+			return err
+		}
+	}
+
+	now, err := txTimeRFC3339(ctx)
+	if err != nil {
+		return err
+	}
+
+	before := *child
+	child.ParentID = parent.ID
+	child.UpdatedAt = now
+	child.Version++
+
+	if err := c.saveAsset(ctx, "AttachComponent", &before, child); err != nil {
+		return err
+	}
+	if err := emitOperation(ctx, "AttachComponent", child, child.Owner, a); err != nil {
+		return err
+	}
+	return guard.complete(ctx, "")
+}
+
+func (c *AssetContract) DetachComponent(ctx contractapi.TransactionContextInterface, parentID string, childID string) error {
+	guard, err := beginRequest(ctx, "DetachComponent", parentID, childID)
+	if err != nil {
+		return err
+	}
+	if _, ok := guard.replayed(); ok {
+		return nil
+	}
+
+	if err := requireOwnerMSP(ctx); err != nil {
+		return err
+	}
+
+	child, err := c.ReadAsset(ctx, childID)
+	if err != nil {
+		return err
+	}
+	if child.ParentID != strings.TrimSpace(parentID) {
+		return fmt.Errorf("asset %s is not a component of %s", child.ID, strings.TrimSpace(parentID))
+	}
+	a, err := authorize(ctx, child, RightTransfer)
+	if err != nil {
+		return err
+	}
+	if err := checkNotFrozen(ctx, child.ID); err != nil {
+		return err
+	}
+
+	now, err := txTimeRFC3339(ctx)
+	if err != nil {
+		return err
+	}
+
+	before := *child
+	child.ParentID = ""
+	child.UpdatedAt = now
+	child.Version++
+
+	if err := c.saveAsset(ctx, "DetachComponent", &before, child); err != nil {
+		return err
+	}
+	if err := emitOperation(ctx, "DetachComponent", child, child.Owner, a); err != nil {
+		return err
+	}
+	return guard.complete(ctx, "")
+}
+
+func (c *AssetContract) GetAssetTree(ctx contractapi.TransactionContextInterface, id string) (*AssetTree, error) {
+	asset, err := c.ReadAsset(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return assetTree(ctx, asset)
+}
+
+func assetTree(ctx contractapi.TransactionContextInterface, asset *Asset) (*AssetTree, error) {
+	tree := &AssetTree{Asset: asset}
+	children, err := componentIDs(ctx, asset.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, childID := range children {
+		child, err := readAsset(ctx, childID)
+		if err != nil {
+			return nil, err
+		}
+		subtree, err := assetTree(ctx, child)
+		if err != nil {
+			return nil, err
+		}
+		tree.Components = append(tree.Components, subtree)
+	}
+	return tree, nil
+}
+
+// componentIDs returns the IDs of the direct components of an asset.
+func componentIDs(ctx contractapi.TransactionContextInterface, parentID string) ([]string, error) {
+	iter, err := ctx.GetStub().GetStateByPartialCompositeKey(componentIndex, []string{parentID})
+	if err != nil {
+		return nil, fmt.Errorf("component query: %w", err)
+	}
+	defer iter.Close()
+
+	var ids []string
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return nil, fmt.Errorf("iter next: %w", err)
+		}
+		_, attrs, err := ctx.GetStub().SplitCompositeKey(kv.Key)
+		if err != nil {
+			return nil, fmt.Errorf("split index key: %w", err)
+		}
+		ids = append(ids, attrs[1])
+	}
+	return ids, nil
+}
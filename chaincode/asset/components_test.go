@@ -0,0 +1,86 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAttachComponent(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("car", "Alice", "10000 EUR")
+	e.createAsset("engine", "Alice", "3000 EUR")
+	e.createAsset("piston", "Alice", "50 EUR")
+	e.createAsset("bike", "Bob", "500 EUR")
+
+	wantErr(t, e.assets.AttachComponent(e.tx(alice), "car", "car"), "cannot be a component of itself")
+	wantErr(t, e.assets.AttachComponent(e.tx(alice), "car", "bike"), "owned by Bob")
+	mustOK(t, e.assets.AttachComponent(e.tx(alice), "car", "engine"))
+	mustOK(t, e.assets.AttachComponent(e.tx(alice), "engine", "piston"))
+	wantErr(t, e.assets.AttachComponent(e.tx(alice), "car", "engine"), "already a component of car")
+	wantErr(t, e.assets.AttachComponent(e.tx(alice), "piston", "car"), "would create a cycle")
+
+	tree, err := e.assets.GetAssetTree(e.tx(bob), "car")
+	mustOK(t, err)
+	if len(tree.Components) != 1 || tree.Components[0].Asset.ID != "engine" ||
+		len(tree.Components[0].Components) != 1 || tree.Components[0].Components[0].Asset.ID != "piston" {
+		t.Fatalf("unexpected tree %+v", tree)
+	}
+
+	// Components move with their parent and cannot be transferred alone.
+	wantErr(t, e.assets.UpdateAssetOwner(e.tx(alice), "engine", "Bob"), "is a component of car")
+	mustOK(t, e.assets.UpdateAssetOwner(e.tx(alice), "car", "Bob"))
+	for _, id := range []string{"car", "engine", "piston"} {
+		if owner := e.readAsset(id).Owner; owner != "Bob" {
+			t.Errorf("%s owner = %s, want Bob", id, owner)
+		}
+	}
+}
+
+func TestDetachComponent(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("car", "Alice", "10000 EUR")
+	e.createAsset("engine", "Alice", "3000 EUR")
+	mustOK(t, e.assets.AttachComponent(e.tx(alice), "car", "engine"))
+
+	wantErr(t, e.assets.DetachComponent(e.tx(alice), "other", "engine"), "is not a component of other")
+	mustOK(t, e.assets.DetachComponent(e.tx(alice), "car", "engine"))
+	if parent := e.readAsset("engine").ParentID; parent != "" {
+		t.Fatalf("parent = %s", parent)
+	}
+	tree, err := e.assets.GetAssetTree(e.tx(alice), "car")
+	mustOK(t, err)
+	if len(tree.Components) != 0 {
+		t.Fatalf("components = %d", len(tree.Components))
+	}
+	_, err = e.assets.GetAssetTree(e.tx(alice), "missing")
+	wantErr(t, err, "not found")
+}
+
+func TestComponentsKeepTransferChecks(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("truck", alice.id(), "40000 EUR")
+	e.createAsset("trailer", alice.id(), "8000 EUR")
+	e.createAsset("crane", alice.id(), "15000 EUR")
+	e.verifyOwners(carol.id())
+
+	now := e.ledger.clock
+	start := now.Add(-time.Hour).Format(time.RFC3339)
+	end := now.Add(48 * time.Hour).Format(time.RFC3339)
+
+	// An operator of the child alone cannot tie it to the parent.
+	mustOK(t, e.assets.GrantOperator(e.tx(alice), bob.id(), "crane", []string{RightTransfer}, ""))
+	wantErr(t, e.assets.AttachComponent(e.tx(bob), "truck", "crane"), "only the owner of asset truck or an operator with the transfer right")
+
+	mustOK(t, e.assets.LeaseAsset(e.tx(alice), "crane", bob.id(), start, end, LeaseTerms{}))
+	wantErr(t, e.assets.AttachComponent(e.tx(alice), "truck", "crane"), "asset crane is leased until")
+
+	// A component leased after attaching still holds back its parent.
+	mustOK(t, e.assets.AttachComponent(e.tx(alice), "truck", "trailer"))
+	mustOK(t, e.assets.LeaseAsset(e.tx(alice), "trailer", bob.id(), start, end, LeaseTerms{}))
+	wantErr(t, e.assets.UpdateAssetOwner(e.tx(alice), "truck", carol.id()), "asset trailer is leased until")
+	mustOK(t, e.assets.EndLease(e.tx(alice), "trailer"))
+	mustOK(t, e.assets.UpdateAssetOwner(e.tx(alice), "truck", carol.id()))
+	if owner := e.readAsset("trailer").Owner; owner != carol.id() {
+		t.Fatalf("trailer owner = %s", owner)
+	}
+}
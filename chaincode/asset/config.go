@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const configObjectType = "config"
+
+// ContractConfig holds the validation limits of the contract. Empty fields
+// leave the corresponding rule unrestricted, except for the ID and owner
+// rules, which fall back to built-in defaults.
+type ContractConfig struct {
+	// MaxValues caps asset values per currency, e.g. "1000000.00 EUR".
+	MaxValues []string `json:"maxValues,omitempty" metadata:",optional"`
+	// IDPattern is a regular expression every new asset ID must match.
+	IDPattern string `json:"idPattern"`
+	// IDCharset lists the characters besides ASCII letters and digits that
+	// IDs may contain. Defaults to defaultIDCharset.
+	IDCharset string `json:"idCharset,omitempty" metadata:",optional"`
+	// MaxIDLength and MaxOwnerLength cap IDs and owners; zero selects
+	// defaultMaxIDLength and defaultMaxOwnerLength.
+	MaxIDLength    int `json:"maxIdLength,omitempty" metadata:",optional"`
+	MaxOwnerLength int `json:"maxOwnerLength,omitempty" metadata:",optional"`
+	// ReservedIDPrefixes lists prefixes no ID may start with, in addition to
+	// builtinReservedIDPrefixes.
+	ReservedIDPrefixes []string `json:"reservedIdPrefixes,omitempty" metadata:",optional"`
+	// AllowedOwnerMSPs lists the MSPs whose identities may create assets and
+	// make owner-side changes to them, including auctions, bids, leases,
+	// delegations and NFT transfers. Regulators, arbitrators, appraisers and
+	// compliance act in their own capacity and are not restricted, nor are
+	// custody checkpoints, which are witnessed by any handling org, and
+	// disputes, which prior owners must be able to raise from any org.
+	AllowedOwnerMSPs []string `json:"allowedOwnerMSPs,omitempty" metadata:",optional"`
+	// EnabledFunctions lists the contract functions that may be invoked.
+	// GetConfig and SetConfig are always enabled.
+	EnabledFunctions []string `json:"enabledFunctions,omitempty" metadata:",optional"`
+	UpdatedBy        string   `json:"updatedBy,omitempty" metadata:",optional"`
+	UpdatedAt        string   `json:"updatedAt,omitempty" metadata:",optional"`
+}
+
+func (c *AssetContract) GetConfig(ctx contractapi.TransactionContextInterface) (*ContractConfig, error) {
+	return readConfig(ctx)
+}
+
+// SetConfig replaces the contract configuration. Only admins of the
+// governance MSP may change it.
+func (c *AssetContract) SetConfig(ctx contractapi.TransactionContextInterface, cfg ContractConfig) error {
+	if err := c.requireGovernance(ctx); err != nil {
+		return err
+	}
+	if err := requireOrgAdmin(ctx); err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool)
+	for _, raw := range cfg.MaxValues {
+		m, err := parseValue("maxValues", raw)
+		if err != nil {
+			return err
+		}
+		if seen[m.Currency] {
+			return fmt.Errorf("maxValues has more than one entry for %s", m.Currency)
+		}
+		seen[m.Currency] = true
+	}
+	if _, err := regexp.Compile(cfg.IDPattern); err != nil {
+		return fmt.Errorf("invalid idPattern: %w", err)
+	}
+	if err := cfg.checkValidationRules(); err != nil {
+		return err
+	}
+
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("get msp id: %w", err)
+	}
+	now, err := txTimeRFC3339(ctx)
+	if err != nil {
+		return err
+	}
+	cfg.UpdatedBy = mspID
+	cfg.UpdatedAt = now
+
+	key, err := ctx.GetStub().CreateCompositeKey(configObjectType, []string{})
+	if err != nil {
+		return fmt.Errorf("create config key: %w", err)
+	}
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("marshal config: %w", err)
+	}
+	return ctx.GetStub().PutState(key, b)
+}
+
+// requireGovernance rejects invokers outside the configured governance MSP.
+func (c *AssetContract) requireGovernance(ctx contractapi.TransactionContextInterface) error {
+	governance := c.GovernanceMSP
+	if governance == "" {
+		governance = defaultRegulatorMSP
+	}
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("get msp id: %w", err)
+	}
+	if mspID != governance {
+		return fmt.Errorf("only %s identities may change the contract configuration", governance)
+	}
+	return nil
+}
+
+// checkFunctionEnabled is the contract's BeforeTransaction hook. It rejects
+// functions left out of ContractConfig.EnabledFunctions.
+func checkFunctionEnabled(ctx contractapi.TransactionContextInterface) error {
+	fn, _ := ctx.GetStub().GetFunctionAndParameters()
+	if i := strings.LastIndex(fn, ":"); i >= 0 {
+		fn = fn[i+1:]
+	}
+	// contractapi accepts function names with a lower case first letter.
+	if r := []rune(fn); len(r) > 0 {
+		r[0] = unicode.ToUpper(r[0])
+		fn = string(r)
+	}
+	if fn == "GetConfig" || fn == "SetConfig" {
+		return nil
+	}
+
+	cfg, err := readConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if len(cfg.EnabledFunctions) > 0 && !contains(cfg.EnabledFunctions, fn) {
+		return fmt.Errorf("function %s is disabled by the contract configuration", fn)
+	}
+	return nil
+}
+
+func readConfig(ctx contractapi.TransactionContextInterface) (*ContractConfig, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(configObjectType, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("create config key: %w", err)
+	}
+	b, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("get state: %w", err)
+	}
+	cfg := &ContractConfig{}
+	if b == nil {
+		return cfg, nil
+	}
+	if err := json.Unmarshal(b, cfg); err != nil {
+		return nil, fmt.Errorf("unmarshal config: %w", err)
+	}
+	return cfg, nil
+}
+
+// checkID applies IDPattern to a new asset ID.
+func (cfg *ContractConfig) checkID(id string) error {
+	if cfg.IDPattern == "" {
+		return nil
+	}
+	re, err := regexp.Compile(cfg.IDPattern)
+	if err != nil {
+		return fmt.Errorf("invalid idPattern in config: %w", err)
+	}
+	if !re.MatchString(id) {
+		return fmt.Errorf("id %s does not match the allowed pattern %s", id, cfg.IDPattern)
+	}
+	return nil
+}
+
+// checkValue applies the MaxValues entry for the value's currency.
+func (cfg *ContractConfig) checkValue(name string, m Money) error {
+	for _, raw := range cfg.MaxValues {
+		limit, err := ParseMoney(raw)
+		if err != nil {
+			return fmt.Errorf("invalid maxValues in config: %w", err)
+		}
+		if limit.Currency == m.Currency && m.Amount > limit.Amount {
+			return fmt.Errorf("%s must be <= %s", name, limit)
+		}
+	}
+	return nil
+}
+
+// checkOwnerMSP rejects invokers outside AllowedOwnerMSPs.
+func (cfg *ContractConfig) checkOwnerMSP(ctx contractapi.TransactionContextInterface) error {
+	if len(cfg.AllowedOwnerMSPs) == 0 {
+		return nil
+	}
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("get msp id: %w", err)
+	}
+	if !contains(cfg.AllowedOwnerMSPs, mspID) {
+		return fmt.Errorf("%s identities may not own or manage assets", mspID)
+	}
+	return nil
+}
+
+// requireOwnerMSP applies checkOwnerMSP of the current configuration.
+func requireOwnerMSP(ctx contractapi.TransactionContextInterface) error {
+	cfg, err := readConfig(ctx)
+	if err != nil {
+		return err
+	}
+	return cfg.checkOwnerMSP(ctx)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSetConfig(t *testing.T) {
+	e := newTestEnv(t)
+
+	cfg, err := e.assets.GetConfig(e.tx(alice))
+	mustOK(t, err)
+	if cfg.IDPattern != "" || len(cfg.MaxValues) != 0 {
+		t.Fatalf("default config = %+v", cfg)
+	}
+
+	wantErr(t, e.assets.SetConfig(e.tx(org1Admin), ContractConfig{}), "only Org3MSP identities may change the contract configuration")
+	wantErr(t, e.assets.SetConfig(e.tx(regulator), ContractConfig{}), "only org admin identities")
+	e.assets.GovernanceMSP = "Org1MSP"
+	wantErr(t, e.assets.SetConfig(e.tx(org3Admin), ContractConfig{}), "only Org1MSP identities may change the contract configuration")
+	mustOK(t, e.assets.SetConfig(e.tx(org1Admin), ContractConfig{}))
+	e.assets.GovernanceMSP = ""
+	wantErr(t, e.assets.SetConfig(e.tx(org3Admin), ContractConfig{IDPattern: "("}), "invalid idPattern")
+	wantErr(t, e.assets.SetConfig(e.tx(org3Admin), ContractConfig{MaxValues: []string{"1 EUR", "2 EUR"}}), "more than one entry for EUR")
+
+	mustOK(t, e.assets.SetConfig(e.tx(org3Admin), ContractConfig{
+		IDPattern:        `^asset-[0-9]+$`,
+		MaxValues:        []string{"1000 EUR"},
+		AllowedOwnerMSPs: []string{"Org1MSP"},
+	}))
+	cfg, err = e.assets.GetConfig(e.tx(alice))
+	mustOK(t, err)
+	if cfg.UpdatedBy != "Org3MSP" || cfg.UpdatedAt == "" {
+		t.Fatalf("config not stamped: %+v", cfg)
+	}
+
+	e.verifyOwners("Alice")
+	wantErr(t, e.assets.CreateAsset(e.tx(alice), "other", "Alice", "1 EUR"), "does not match the allowed pattern")
+	wantErr(t, e.assets.CreateAsset(e.tx(alice), "asset-1", "Alice", "1000.01 EUR"), "value must be <= 1000.00 EUR")
+	wantErr(t, e.assets.CreateAsset(e.tx(bob), "asset-1", "Alice", "1 EUR"), "Org2MSP identities may not own or manage assets")
+	mustOK(t, e.assets.CreateAsset(e.tx(alice), "asset-1", "Alice", "5000 USD"))
+	wantErr(t, e.assets.UpdateAssetValue(e.tx(alice), "asset-1", "2000 EUR"), "newValue must be <= 1000.00 EUR")
+}
+
+func TestCheckFunctionEnabled(t *testing.T) {
+	e := newTestEnv(t)
+	mustOK(t, e.assets.SetConfig(e.tx(org3Admin), ContractConfig{EnabledFunctions: []string{"ReadAsset"}}))
+
+	for fn, enabled := range map[string]bool{
+		"ReadAsset":                true,
+		"AssetContract:readAsset":  true,
+		"DeleteAsset":              false,
+		"AssetContract:SetConfig":  true,
+		"GetConfig":                true,
+		"NFTContract:TransferFrom": false,
+	} {
+		err := checkFunctionEnabled(e.ledger.newTx(alice, fn))
+		if enabled && err != nil {
+			t.Errorf("%s: unexpected error %v", fn, err)
+		}
+		if !enabled {
+			wantErr(t, err, "is disabled by the contract configuration")
+		}
+	}
+}
+
+func TestOwnerMSPOnEveryOwnerPath(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("bike", alice.id(), "10 EUR")
+	mustOK(t, e.assets.SetConfig(e.tx(org3Admin), ContractConfig{AllowedOwnerMSPs: []string{"Org2MSP"}}))
+
+	opening := `{"value":"5 EUR","salt":"` + salt + `"}`
+	_, bidErr := e.assets.SubmitBid(e.tx(alice), "auc1")
+	_, sharesErr := e.assets.TransferShares(e.tx(alice), "bike", alice.id(), bob.id(), 100)
+	for name, err := range map[string]error{
+		"CreateAuction":     e.assets.CreateAuction(e.tx(alice), "auc1", "bike", "EUR"),
+		"SubmitBid":         bidErr,
+		"CloseAuction":      e.assets.CloseAuction(e.tx(alice), "auc1"),
+		"RevealBid":         e.assets.RevealBid(e.tx(alice), "auc1", "tx"),
+		"CancelAuction":     e.assets.CancelAuction(e.tx(alice), "auc1"),
+		"CommitAssetValue":  e.assets.CommitAssetValue(e.tx(alice).withTransient(openingTransientKey, opening), "bike"),
+		"RevealValue":       e.assets.RevealValue(e.tx(alice), "bike", "5 EUR", salt),
+		"AttachComponent":   e.assets.AttachComponent(e.tx(alice), "bike", "wheel"),
+		"DetachComponent":   e.assets.DetachComponent(e.tx(alice), "bike", "wheel"),
+		"GrantOperator":     e.assets.GrantOperator(e.tx(alice), bob.id(), "bike", []string{RightTransfer}, ""),
+		"RevokeOperator":    e.assets.RevokeOperator(e.tx(alice), bob.id(), "bike"),
+		"AnchorDocument":    e.assets.AnchorDocument(e.tx(alice), "bike", "invoice", strings.Repeat("ab", 32), ""),
+		"LeaseAsset":        e.assets.LeaseAsset(e.tx(alice), "bike", bob.id(), "", "", LeaseTerms{}),
+		"EndLease":          e.assets.EndLease(e.tx(alice), "bike"),
+		"TransferFrom":      e.nft.TransferFrom(e.tx(alice), alice.id(), bob.id(), "bike"),
+		"Approve":           e.nft.Approve(e.tx(alice), bob.id(), "bike"),
+		"SetApprovalForAll": e.nft.SetApprovalForAll(e.tx(alice), bob.id(), true),
+		"TransferShares":    sharesErr,
+		"SetAssetTags":      e.assets.SetAssetTags(e.tx(alice), "bike", []string{"red"}),
+		"ReviewValuation":   e.assets.ReviewValuation(e.tx(alice), "bike", "v1", true, ""),
+		"UpdateAssetOwner":  e.assets.UpdateAssetOwner(e.tx(alice), "bike", "Bob"),
+		"UpdateAssetValue":  e.assets.UpdateAssetValue(e.tx(alice), "bike", "11 EUR"),
+		"DeleteAsset":       e.assets.DeleteAsset(e.tx(alice), "bike"),
+	} {
+		if err == nil || !strings.Contains(err.Error(), "Org1MSP identities may not own or manage assets") {
+			t.Errorf("%s: got %v, want the owner MSP error", name, err)
+		}
+	}
+
+	// Custody checkpoints are witnessed by whichever org handles the asset.
+	mustOK(t, e.assets.RecordCheckpoint(e.tx(alice), "bike", "depot", "courier", "", ""))
+}
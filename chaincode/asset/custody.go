@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const custodyObjectType = "custody"
+
+// CustodyEvent is a checkpoint on the physical journey of an asset,
+// witnessed by the org that recorded it.
+type CustodyEvent struct {
+	AssetID   string `json:"assetId"`
+	Location  string `json:"location"`
+	Handler   string `json:"handler"`
+	Condition string `json:"condition,omitempty" metadata:",optional"`
+	// Readings holds sensor readings as a compact JSON object, e.g.
+	// {"temperatureC":4.5,"sealIntact":true}.
+	Readings   string `json:"readings,omitempty" metadata:",optional"`
+	WitnessMSP string `json:"witnessMsp"`
+	RecordedBy string `json:"recordedBy"`
+	RecordedAt string `json:"recordedAt"`
+	TxID       string `json:"txId"`
+}
+
+// RecordCheckpoint appends a custody event to the chain of an asset. The
+// invoking org is recorded as witness. readingsJSON may be empty.
+func (c *AssetContract) RecordCheckpoint(ctx contractapi.TransactionContextInterface, assetID string, location string, handler string, condition string, readingsJSON string) error {
+	guard, err := beginRequest(ctx, "RecordCheckpoint", assetID, location, handler, condition, readingsJSON)
+	if err != nil {
+		return err
+	}
+	if _, ok := guard.replayed(); ok {
+		return nil
+	}
+
+	location = strings.TrimSpace(location)
+	if location == "" {
+		return errors.New("location is required")
+	}
+	handler = strings.TrimSpace(handler)
+	if handler == "" {
+		return errors.New("handler is required")
+	}
+	readings, err := compactReadings(readingsJSON)
+	if err != nil {
+		return err
+	}
+
+	// ReadAsset fails for deleted assets, so their chain is closed.
+	asset, err := c.ReadAsset(ctx, assetID)
+	if err != nil {
+		return err
+	}
+	if err := checkNotFrozen(ctx, asset.ID); err != nil {
+		return err
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("get msp id: %w", err)
+	}
+	recordedBy, err := submitterID(ctx)
+	if err != nil {
+		return err
+	}
+
+	event := CustodyEvent{
+		AssetID:    asset.ID,
+		Location:   location,
+		Handler:    handler,
+		Condition:  strings.TrimSpace(condition),
+		Readings:   readings,
+		WitnessMSP: mspID,
+		RecordedBy: recordedBy,
+		RecordedAt: now.Format(indexTimeLayout),
+		TxID:       ctx.GetStub().GetTxID(),
+	}
+	// Keys sort by time, so the chain reads in order.
+	key, err := ctx.GetStub().CreateCompositeKey(custodyObjectType, []string{asset.ID, event.RecordedAt, event.TxID})
+	if err != nil {
+		return fmt.Errorf("create custody key: %w", err)
+	}
+	b, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshal custody event: %w", err)
+	}
+	if err := ctx.GetStub().PutState(key, b); err != nil {
+		return fmt.Errorf("put state: %w", err)
+	}
+	if err := recordRelatedAudit(ctx, "RecordCheckpoint", asset.ID, "custody", nil, event); err != nil {
+		return err
+	}
+	return guard.complete(ctx, "")
+}
+
+// GetCustodyChain returns the custody events of an asset, oldest first. The
+// chain of a deleted asset remains readable.
+func (c *AssetContract) GetCustodyChain(ctx contractapi.TransactionContextInterface, id string) ([]*CustodyEvent, error) {
+	id, err := existingID("id", id)
+	if err != nil {
+		return nil, err
+	}
+
+	iter, err := ctx.GetStub().GetStateByPartialCompositeKey(custodyObjectType, []string{id})
+	if err != nil {
+		return nil, fmt.Errorf("custody query: %w", err)
+	}
+	defer iter.Close()
+
+	out := []*CustodyEvent{}
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return nil, fmt.Errorf("iter next: %w", err)
+		}
+		var e CustodyEvent
+		if err := json.Unmarshal(kv.Value, &e); err != nil {
+			return nil, fmt.Errorf("unmarshal custody event: %w", err)
+		}
+		out = append(out, &e)
+	}
+	return out, nil
+}
+
+// compactReadings checks that readingsJSON is empty or a JSON object and
+// returns it compacted.
+func compactReadings(readingsJSON string) (string, error) {
+	readingsJSON = strings.TrimSpace(readingsJSON)
+	if readingsJSON == "" {
+		return "", nil
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(readingsJSON), &obj); err != nil {
+		return "", fmt.Errorf("readingsJSON must be a JSON object: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, []byte(readingsJSON)); err != nil {
+		return "", fmt.Errorf("compact readings: %w", err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRecordCheckpoint(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("crate", "Alice", "100 EUR")
+
+	mustOK(t, e.assets.RecordCheckpoint(e.tx(alice), "crate", "Rotterdam", "ACME Freight", "sealed", `{ "temperatureC": 4.5, "sealIntact": true }`))
+	mustOK(t, e.assets.RecordCheckpoint(e.tx(bob), "crate", "Hamburg", "Nordic Haul", "", ""))
+
+	chain, err := e.assets.GetCustodyChain(e.tx(carol), "crate")
+	mustOK(t, err)
+	if len(chain) != 2 {
+		t.Fatalf("chain has %d events, want 2", len(chain))
+	}
+	if chain[0].Location != "Rotterdam" || chain[0].WitnessMSP != "Org1MSP" || chain[0].Readings != `{"temperatureC":4.5,"sealIntact":true}` {
+		t.Fatalf("unexpected first event %+v", chain[0])
+	}
+	if chain[1].Location != "Hamburg" || chain[1].WitnessMSP != "Org2MSP" || chain[1].RecordedBy != bob.id() {
+		t.Fatalf("unexpected second event %+v", chain[1])
+	}
+
+	trail, err := e.assets.GetAuditTrail(e.tx(carol), "crate", 10, "")
+	mustOK(t, err)
+	if n := len(trail.Records); n != 3 || trail.Records[2].Function != "RecordCheckpoint" || trail.Records[2].MSPID != "Org2MSP" {
+		t.Fatalf("audit trail %+v", trail.Records)
+	}
+	if c := trail.Records[2].Changes; len(c) != 1 || c[0].Field != "custody" || c[0].Before != "" || !strings.Contains(c[0].After, `"location":"Hamburg"`) {
+		t.Fatalf("checkpoint changes %+v", c)
+	}
+
+	wantErr(t, e.assets.RecordCheckpoint(e.tx(alice), "crate", " ", "h", "", ""), "location is required")
+	wantErr(t, e.assets.RecordCheckpoint(e.tx(alice), "crate", "l", " ", "", ""), "handler is required")
+	wantErr(t, e.assets.RecordCheckpoint(e.tx(alice), "crate", "l", "h", "", "[1,2]"), "readingsJSON must be a JSON object")
+
+	mustOK(t, e.assets.FreezeAsset(e.tx(regulator), "crate", "CASE-9"))
+	wantErr(t, e.assets.RecordCheckpoint(e.tx(alice), "crate", "l", "h", "", ""), "frozen under case CASE-9")
+	mustOK(t, e.assets.UnfreezeAsset(e.tx(regulator), "crate"))
+
+	mustOK(t, e.assets.DeleteAsset(e.tx(alice), "crate"))
+	wantErr(t, e.assets.RecordCheckpoint(e.tx(alice), "crate", "l", "h", "", ""), "asset crate not found")
+	chain, err = e.assets.GetCustodyChain(e.tx(carol), "crate")
+	mustOK(t, err)
+	if len(chain) != 2 {
+		t.Fatalf("chain of deleted asset has %d events", len(chain))
+	}
+
+	_, err = e.assets.GetCustodyChain(e.tx(carol), "")
+	wantErr(t, err, "id is required")
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGrantOperator(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("gold", alice.id(), "1000 EUR")
+	e.createAsset("silver", alice.id(), "100 EUR")
+	e.verifyOwners(carol.id())
+
+	wantErr(t, e.assets.GrantOperator(e.tx(alice), " ", "gold", []string{RightTransfer}, ""), "operator is required")
+	wantErr(t, e.assets.GrantOperator(e.tx(alice), alice.id(), "gold", []string{RightTransfer}, ""), "cannot delegate to yourself")
+	wantErr(t, e.assets.GrantOperator(e.tx(alice), bob.id(), "gold", nil, ""), "at least one right is required")
+	wantErr(t, e.assets.GrantOperator(e.tx(alice), bob.id(), "gold", []string{"sell"}, ""), `unknown right "sell"`)
+	wantErr(t, e.assets.GrantOperator(e.tx(alice), bob.id(), "gold", []string{RightTransfer}, "tomorrow"), "invalid expiresAt")
+	wantErr(t, e.assets.GrantOperator(e.tx(alice), bob.id(), "gold", []string{RightTransfer}, "2000-01-01T00:00:00Z"), "must be in the future")
+	wantErr(t, e.assets.GrantOperator(e.tx(carol), bob.id(), "gold", []string{RightTransfer}, ""), "only the owner of asset gold may delegate")
+
+	mustOK(t, e.assets.GrantOperator(e.tx(alice), bob.id(), "gold", []string{RightUpdateValue}, ""))
+
+	// The right is scoped to gold and to value updates.
+	wantErr(t, e.assets.UpdateAssetValue(e.tx(bob), "silver", "1 EUR"), "updateValue right")
+	wantErr(t, e.assets.UpdateAssetOwner(e.tx(bob), "gold", carol.id()), "transfer right")
+	ctx := e.tx(bob)
+	mustOK(t, e.assets.UpdateAssetValue(ctx, "gold", "2000 EUR"))
+	var ev OperationEvent
+	eventOf(t, ctx, &ev)
+	if ev.Actor != bob.id() || ev.Owner != alice.id() || !ev.Operator || ev.Function != "UpdateAssetValue" {
+		t.Fatalf("unexpected event %+v", ev)
+	}
+
+	// A grant over all assets covers silver too.
+	mustOK(t, e.assets.GrantOperator(e.tx(alice), bob.id(), allAssets, []string{RightTransfer}, ""))
+	mustOK(t, e.assets.UpdateAssetOwner(e.tx(bob), "silver", carol.id()))
+
+	delegations, err := e.assets.GetDelegations(e.tx(carol), alice.id())
+	mustOK(t, err)
+	if len(delegations) != 2 {
+		t.Fatalf("got %d delegations, want 2", len(delegations))
+	}
+
+	mustOK(t, e.assets.RevokeOperator(e.tx(alice), bob.id(), allAssets))
+	wantErr(t, e.assets.UpdateAssetOwner(e.tx(bob), "gold", carol.id()), "transfer right")
+	wantErr(t, e.assets.RevokeOperator(e.tx(alice), bob.id(), allAssets), "no delegation")
+
+	// Grants are keyed and audited by the asset ID, whatever the spacing of
+	// the scope argument.
+	mustOK(t, e.assets.GrantOperator(e.tx(alice), bob.id(), " gold ", []string{RightTransfer}, ""))
+	mustOK(t, e.assets.RevokeOperator(e.tx(alice), bob.id(), "gold"))
+	trail, err := e.assets.GetAuditTrail(e.tx(carol), "gold", 10, "")
+	mustOK(t, err)
+	var functions []string
+	for _, rec := range trail.Records {
+		functions = append(functions, rec.Function)
+	}
+	if got := strings.Join(functions, ","); got != "CreateAsset,GrantOperator,UpdateAssetValue,GrantOperator,RevokeOperator" {
+		t.Fatalf("trail = %s", got)
+	}
+	regrant, revoke := trail.Records[3].Changes[0], trail.Records[4].Changes[0]
+	if regrant.Field != "delegation" || !strings.Contains(regrant.Before, `"rights":["updateValue"]`) || !strings.Contains(regrant.After, `"rights":["transfer"]`) {
+		t.Fatalf("grant change %+v", regrant)
+	}
+	if revoke.Before != regrant.After || revoke.After != "" {
+		t.Fatalf("revoke change %+v", revoke)
+	}
+	trail, err = e.assets.GetAuditTrail(e.tx(carol), allAssets, 10, "")
+	mustOK(t, err)
+	if len(trail.Records) != 2 || trail.Records[1].Function != "RevokeOperator" {
+		t.Fatalf("trail of %s = %+v", allAssets, trail.Records)
+	}
+}
+
+func TestGrantOperatorExpiry(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("gold", alice.id(), "1000 EUR")
+
+	expires := e.ledger.clock.Add(time.Hour).Format(time.RFC3339)
+	mustOK(t, e.assets.GrantOperator(e.tx(alice), bob.id(), "gold", []string{RightUpdateValue}, expires))
+	mustOK(t, e.assets.UpdateAssetValue(e.tx(bob), "gold", "1 EUR"))
+
+	e.ledger.advance(time.Hour)
+	wantErr(t, e.assets.UpdateAssetValue(e.tx(bob), "gold", "2 EUR"), "updateValue right")
+}
+
+func TestOperatorActsOnBehalfOfHolder(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("house", alice.id(), "500000 EUR")
+	e.verifyOwners(carol.id())
+	mustOK(t, e.assets.GrantOperator(e.tx(alice), bob.id(), "house", []string{RightTransfer}, ""))
+
+	// bob consents for alice, carol for herself.
+	transfer, err := e.assets.TransferShares(e.tx(bob), "house", alice.id(), carol.id(), 2500)
+	mustOK(t, err)
+	if len(transfer.Consents) != 1 || transfer.Consents[0] != alice.id() {
+		t.Fatalf("consents = %v", transfer.Consents)
+	}
+	transfer, err = e.assets.TransferShares(e.tx(carol), "house", alice.id(), carol.id(), 2500)
+	mustOK(t, err)
+	if !transfer.Applied {
+		t.Fatal("transfer not applied")
+	}
+}
+
+func TestOperatorsRecordedOnEveryPath(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("gold", alice.id(), "1000 EUR")
+	e.createAsset("silver", alice.id(), "100 EUR")
+	mustOK(t, e.assets.GrantOperator(e.tx(alice), carol.id(), allAssets, []string{RightTransfer, RightLease, RightUpdateValue}, ""))
+
+	expect := func(ctx *mockContext, function string, operator bool) {
+		t.Helper()
+		var ev OperationEvent
+		if name := eventOf(t, ctx, &ev); name != "AssetOperation" || ev.Function != function || ev.Actor != carol.id() || ev.Operator != operator || ev.Owner != alice.id() {
+			t.Fatalf("event %s %+v, want %s by carol", name, ev, function)
+		}
+	}
+	for _, step := range []struct {
+		function string
+		call     func(*mockContext) error
+	}{
+		{"CreateAuction", func(ctx *mockContext) error { return e.assets.CreateAuction(ctx, "auc1", "gold", "EUR") }},
+		{"CloseAuction", func(ctx *mockContext) error { return e.assets.CloseAuction(ctx, "auc1") }},
+		{"EndAuction", func(ctx *mockContext) error { return e.assets.EndAuction(ctx, "auc1") }},
+		{"LeaseAsset", func(ctx *mockContext) error {
+			start := e.ledger.clock.Add(time.Hour).Format(time.RFC3339)
+			end := e.ledger.clock.Add(48 * time.Hour).Format(time.RFC3339)
+			return e.assets.LeaseAsset(ctx, "silver", bob.id(), start, end, LeaseTerms{})
+		}},
+	} {
+		ctx := e.tx(carol)
+		mustOK(t, step.call(ctx))
+		expect(ctx, step.function, true)
+	}
+
+	e.assets.AppraiserMSPs = []string{"Org2MSP"}
+	appraiser := newAppraiser("Org2MSP", "appraiser")
+	id, err := e.assets.SubmitValuation(e.tx(appraiser), "gold", "1100 EUR", "comparable sales", "2023-12-01")
+	mustOK(t, err)
+	mustOK(t, e.assets.ReviewValuation(e.tx(carol), "gold", id, true, ""))
+	valuations, err := e.assets.GetValuations(e.tx(carol), "gold")
+	mustOK(t, err)
+	if v := valuations[0]; v.ReviewedBy != carol.id() || v.ReviewedFor != alice.id() {
+		t.Fatalf("valuation %+v, want reviewed by carol for alice", v)
+	}
+
+	ctx := e.tx(alice)
+	mustOK(t, e.assets.RevokeOperator(ctx, carol.id(), allAssets))
+	var ev OperationEvent
+	if name := eventOf(t, ctx, &ev); name != "AssetOperation" || ev.Function != "RevokeOperator" || ev.AssetID != allAssets || ev.Actor != alice.id() {
+		t.Fatalf("event %s %+v", name, ev)
+	}
+}
+
+func TestLabelOwnedAssetsLeaseAndAuction(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("statue", "Gallery", "5000 EUR")
+	e.createAsset("vase", "Gallery", "800 EUR")
+
+	start := e.ledger.clock.Add(time.Hour).Format(time.RFC3339)
+	end := e.ledger.clock.Add(48 * time.Hour).Format(time.RFC3339)
+	mustOK(t, e.assets.LeaseAsset(e.tx(bob), "statue", carol.id(), start, end, LeaseTerms{}))
+	mustOK(t, e.assets.EndLease(e.tx(bob), "statue"))
+	mustOK(t, e.assets.CreateAuction(e.tx(bob), "auc1", "vase", "EUR"))
+	mustOK(t, e.assets.CloseAuction(e.tx(carol), "auc1"))
+}
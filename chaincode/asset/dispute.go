@@ -0,0 +1,446 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	disputeObjectType = "dispute"
+
+	DisputeOpen     = "open"
+	DisputeResolved = "resolved"
+)
+
+var errOpenDispute = errors.New("open dispute")
+
+// Dispute is a contested ownership claim on an asset. While it is open the
+// asset cannot change owner.
+type Dispute struct {
+	ID         string      `json:"id"`
+	AssetID    string      `json:"assetId"`
+	Claimant   string      `json:"claimant"`
+	Respondent string      `json:"respondent"`
+	Claim      string      `json:"claim"`
+	Status     string      `json:"status"`
+	OpenedAt   string      `json:"openedAt"`
+	Evidence   []*Evidence `json:"evidence"`
+	Decision   string      `json:"decision,omitempty" metadata:",optional"`
+	// RestoredOwner is the prior owner the asset was returned to, if the
+	// resolution reverted ownership.
+	RestoredOwner string `json:"restoredOwner,omitempty" metadata:",optional"`
+	ResolvedBy    string `json:"resolvedBy,omitempty" metadata:",optional"`
+	ResolvedAt    string `json:"resolvedAt,omitempty" metadata:",optional"`
+}
+
+type Evidence struct {
+	SubmittedBy string `json:"submittedBy"`
+	Statement   string `json:"statement"`
+	SHA256      string `json:"sha256,omitempty" metadata:",optional"`
+	SubmittedAt string `json:"submittedAt"`
+	TxID        string `json:"txId"`
+}
+
+// OpenDispute contests the ownership of an asset. The claimant must be the
+// current owner or a prior owner. Label owners carry no identity, so an asset
+// currently owned by a label may be disputed by whoever authorize admits for
+// it; prior label owners admit nobody. The current owner is recorded as
+// respondent.
+func (c *AssetContract) OpenDispute(ctx contractapi.TransactionContextInterface, assetID string, claim string) error {
+	guard, err := beginRequest(ctx, "OpenDispute", assetID, claim)
+	if err != nil {
+		return err
+	}
+	if _, ok := guard.replayed(); ok {
+		return nil
+	}
+
+	claim = strings.TrimSpace(claim)
+	if claim == "" {
+		return errors.New("claim is required")
+	}
+
+	asset, err := c.ReadAsset(ctx, assetID)
+	if err != nil {
+		return err
+	}
+	if asset.ParentID != "" {
+		return fmt.Errorf("asset %s is a component of %s; dispute the parent asset instead", asset.ID, asset.ParentID)
+	}
+	if err := checkNoOpenDispute(ctx, asset.ID); err != nil {
+		return err
+	}
+
+	claimant, err := submitterID(ctx)
+	if err != nil {
+		return err
+	}
+	owners, err := ownerHistory(ctx, asset.ID)
+	if err != nil {
+		return err
+	}
+	party := false
+	for _, owner := range owners {
+		if owner == claimant {
+			party = true
+			break
+		}
+	}
+	if !party && !isIdentity(asset.Owner) {
+		if _, err := authorize(ctx, asset, RightTransfer); err != nil {
+			return err
+		}
+		party = true
+	}
+	if !party {
+		return fmt.Errorf("only the current or a prior owner of asset %s may open a dispute", asset.ID)
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	dispute := &Dispute{
+		ID:         ctx.GetStub().GetTxID(),
+		AssetID:    asset.ID,
+		Claimant:   claimant,
+		Respondent: asset.Owner,
+		Claim:      claim,
+		Status:     DisputeOpen,
+		OpenedAt:   now.Format(indexTimeLayout),
+		Evidence:   []*Evidence{},
+	}
+	// Keys sort by opening time, so GetDisputes lists them in order.
+	key, err := ctx.GetStub().CreateCompositeKey(disputeObjectType, []string{asset.ID, dispute.OpenedAt, dispute.ID})
+	if err != nil {
+		return fmt.Errorf("create dispute key: %w", err)
+	}
+	if err := putDispute(ctx, key, dispute); err != nil {
+		return err
+	}
+	if err := recordRelatedAudit(ctx, "OpenDispute", asset.ID, "dispute", nil, dispute); err != nil {
+		return err
+	}
+	if err := setEvent(ctx, "DisputeOpened", dispute); err != nil {
+		return err
+	}
+	return guard.complete(ctx, "")
+}
+
+// SubmitEvidence adds a statement, optionally backed by the SHA-256 digest of
+// an off-chain document, to the open dispute of an asset. The claimant, the
+// respondent and arbitrators may submit evidence.
+func (c *AssetContract) SubmitEvidence(ctx contractapi.TransactionContextInterface, assetID string, statement string, sha256 string) error {
+	guard, err := beginRequest(ctx, "SubmitEvidence", assetID, statement, sha256)
+	if err != nil {
+		return err
+	}
+	if _, ok := guard.replayed(); ok {
+		return nil
+	}
+
+	statement = strings.TrimSpace(statement)
+	if statement == "" {
+		return errors.New("statement is required")
+	}
+	var digest string
+	if strings.TrimSpace(sha256) != "" {
+		if digest, err = normalizeSHA256(sha256); err != nil {
+			return err
+		}
+	}
+
+	assetID, err = existingID("assetId", assetID)
+	if err != nil {
+		return err
+	}
+	if err := checkNotFrozen(ctx, assetID); err != nil {
+		return err
+	}
+	dispute, key, err := readOpenDispute(ctx, assetID)
+	if err != nil {
+		return err
+	}
+	if dispute == nil {
+		return fmt.Errorf("asset %s has no open dispute", assetID)
+	}
+
+	submitter, err := submitterID(ctx)
+	if err != nil {
+		return err
+	}
+	if submitter != dispute.Claimant && submitter != dispute.Respondent && isIdentity(dispute.Respondent) {
+		arbitrator, err := c.isArbitrator(ctx)
+		if err != nil {
+			return err
+		}
+		if !arbitrator {
+			return fmt.Errorf("only the parties to the dispute on asset %s or an arbitrator may submit evidence", assetID)
+		}
+	}
+
+	now, err := txTimeRFC3339(ctx)
+	if err != nil {
+		return err
+	}
+	before := *dispute
+	dispute.Evidence = append(dispute.Evidence, &Evidence{
+		SubmittedBy: submitter,
+		Statement:   statement,
+		SHA256:      digest,
+		SubmittedAt: now,
+		TxID:        ctx.GetStub().GetTxID(),
+	})
+	if err := putDispute(ctx, key, dispute); err != nil {
+		return err
+	}
+	if err := recordRelatedAudit(ctx, "SubmitEvidence", assetID, "dispute", &before, dispute); err != nil {
+		return err
+	}
+	return guard.complete(ctx, "")
+}
+
+// ResolveDispute closes the open dispute of an asset with the arbitrator's
+// decision. When restoreOwner is set, ownership reverts to that identity,
+// which must be a prior owner of the asset according to its history. Apart
+// from the dispute itself, the reverting transfer is subject to the usual
+// transfer checks.
+func (c *AssetContract) ResolveDispute(ctx contractapi.TransactionContextInterface, assetID string, decision string, restoreOwner string) error {
+	guard, err := beginRequest(ctx, "ResolveDispute", assetID, decision, restoreOwner)
+	if err != nil {
+		return err
+	}
+	if _, ok := guard.replayed(); ok {
+		return nil
+	}
+
+	if err := c.requireArbitrator(ctx); err != nil {
+		return err
+	}
+	decision = strings.TrimSpace(decision)
+	if decision == "" {
+		return errors.New("decision is required")
+	}
+	if strings.TrimSpace(restoreOwner) == "" {
+		restoreOwner = ""
+	} else if restoreOwner, err = validOwner(ctx, "restoreOwner", restoreOwner); err != nil {
+		return err
+	}
+
+	asset, err := c.ReadAsset(ctx, assetID)
+	if err != nil {
+		return err
+	}
+	dispute, key, err := readOpenDispute(ctx, asset.ID)
+	if err != nil {
+		return err
+	}
+	if dispute == nil {
+		return fmt.Errorf("asset %s has no open dispute", asset.ID)
+	}
+
+	if restoreOwner != "" {
+		if restoreOwner == asset.Owner {
+			return fmt.Errorf("%s already owns asset %s", restoreOwner, asset.ID)
+		}
+		owners, err := ownerHistory(ctx, asset.ID)
+		if err != nil {
+			return err
+		}
+		if !contains(owners, restoreOwner) {
+			return fmt.Errorf("%s is not a prior owner of asset %s", restoreOwner, asset.ID)
+		}
+	}
+
+	now, err := txTimeRFC3339(ctx)
+	if err != nil {
+		return err
+	}
+	resolvedBy, err := submitterID(ctx)
+	if err != nil {
+		return err
+	}
+	open := *dispute
+	dispute.Status = DisputeResolved
+	dispute.Decision = decision
+	dispute.RestoredOwner = restoreOwner
+	dispute.ResolvedBy = resolvedBy
+	dispute.ResolvedAt = now
+	if err := putDispute(ctx, key, dispute); err != nil {
+		return err
+	}
+
+	// Reads do not see the writes of their own transaction, so the dispute
+	// still looks open here and the transfer must skip that check.
+	if restoreOwner != "" {
+		err = c.reassignAsset(ctx, "ResolveDispute", asset, restoreOwner)
+	} else {
+		err = recordRelatedAudit(ctx, "ResolveDispute", asset.ID, "dispute", &open, dispute)
+	}
+	if err != nil {
+		return err
+	}
+	if err := setEvent(ctx, "DisputeResolved", dispute); err != nil {
+		return err
+	}
+	return guard.complete(ctx, "")
+}
+
+// GetDisputes returns the open and resolved disputes of an asset, oldest
+// first.
+func (c *AssetContract) GetDisputes(ctx contractapi.TransactionContextInterface, assetID string) ([]*Dispute, error) {
+	assetID, err := existingID("assetId", assetID)
+	if err != nil {
+		return nil, err
+	}
+
+	iter, err := ctx.GetStub().GetStateByPartialCompositeKey(disputeObjectType, []string{assetID})
+	if err != nil {
+		return nil, fmt.Errorf("dispute query: %w", err)
+	}
+	defer iter.Close()
+
+	out := []*Dispute{}
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return nil, fmt.Errorf("iter next: %w", err)
+		}
+		var d Dispute
+		if err := json.Unmarshal(kv.Value, &d); err != nil {
+			return nil, fmt.Errorf("unmarshal dispute: %w", err)
+		}
+		out = append(out, &d)
+	}
+	return out, nil
+}
+
+// checkNoOpenDispute fails with errOpenDispute while the asset is disputed.
+func checkNoOpenDispute(ctx contractapi.TransactionContextInterface, id string) error {
+	dispute, _, err := readOpenDispute(ctx, id)
+	if err != nil {
+		return err
+	}
+	if dispute != nil {
+		return fmt.Errorf("%w: asset %s is disputed by %s since %s", errOpenDispute, id, dispute.Claimant, dispute.OpenedAt)
+	}
+	return nil
+}
+
+// readOpenDispute returns the open dispute of an asset and its key, or nil
+// when there is none.
+func readOpenDispute(ctx contractapi.TransactionContextInterface, id string) (*Dispute, string, error) {
+	iter, err := ctx.GetStub().GetStateByPartialCompositeKey(disputeObjectType, []string{id})
+	if err != nil {
+		return nil, "", fmt.Errorf("dispute query: %w", err)
+	}
+	defer iter.Close()
+
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return nil, "", fmt.Errorf("iter next: %w", err)
+		}
+		var d Dispute
+		if err := json.Unmarshal(kv.Value, &d); err != nil {
+			return nil, "", fmt.Errorf("unmarshal dispute: %w", err)
+		}
+		if d.Status == DisputeOpen {
+			return &d, kv.Key, nil
+		}
+	}
+	return nil, "", nil
+}
+
+func putDispute(ctx contractapi.TransactionContextInterface, key string, d *Dispute) error {
+	b, err := json.Marshal(d)
+	if err != nil {
+		return fmt.Errorf("marshal dispute: %w", err)
+	}
+	if err := ctx.GetStub().PutState(key, b); err != nil {
+		return fmt.Errorf("put state: %w", err)
+	}
+	return nil
+}
+
+// ownerHistory returns the distinct owners of the asset since it was last
+// created, oldest first, including the current owner.
+func ownerHistory(ctx contractapi.TransactionContextInterface, id string) ([]string, error) {
+	iter, err := ctx.GetStub().GetHistoryForKey(id)
+	if err != nil {
+		return nil, fmt.Errorf("history query: %w", err)
+	}
+	defer iter.Close()
+
+	type version struct {
+		at      time.Time
+		owner   string
+		deleted bool
+	}
+	var versions []version
+	for iter.HasNext() {
+		mod, err := iter.Next()
+		if err != nil {
+			return nil, fmt.Errorf("iter next: %w", err)
+		}
+		v := version{deleted: mod.IsDelete}
+		if mod.Timestamp != nil {
+			v.at = time.Unix(mod.Timestamp.Seconds, int64(mod.Timestamp.Nanos))
+		}
+		if !mod.IsDelete {
+			var asset Asset
+			if err := json.Unmarshal(mod.Value, &asset); err != nil {
+				return nil, fmt.Errorf("unmarshal asset: %w", err)
+			}
+			v.owner = asset.Owner
+		}
+		versions = append(versions, v)
+	}
+	// The order of history entries differs between Fabric releases.
+	sort.SliceStable(versions, func(i, j int) bool { return versions[i].at.Before(versions[j].at) })
+
+	var owners []string
+	for _, v := range versions {
+		switch {
+		case v.deleted:
+			owners = nil
+		case !contains(owners, v.owner):
+			owners = append(owners, v.owner)
+		}
+	}
+	return owners, nil
+}
+
+// arbitratorMSP returns the MSP whose identities may resolve disputes.
+func (c *AssetContract) arbitratorMSP() string {
+	if c.ArbitratorMSP != "" {
+		return c.ArbitratorMSP
+	}
+	return defaultRegulatorMSP
+}
+
+func (c *AssetContract) isArbitrator(ctx contractapi.TransactionContextInterface) (bool, error) {
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return false, fmt.Errorf("get msp id: %w", err)
+	}
+	return mspID == c.arbitratorMSP(), nil
+}
+
+// requireArbitrator rejects invokers outside the configured arbitrator MSP.
+func (c *AssetContract) requireArbitrator(ctx contractapi.TransactionContextInterface) error {
+	ok, err := c.isArbitrator(ctx)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("only %s identities may resolve disputes", c.arbitratorMSP())
+	}
+	return nil
+}
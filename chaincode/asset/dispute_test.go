@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDisputeRevertsOwnership(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("car", alice.id(), "20000 EUR")
+	e.verifyOwners(bob.id(), carol.id())
+	mustOK(t, e.assets.UpdateAssetOwner(e.tx(alice), "car", bob.id()))
+
+	wantErr(t, e.assets.OpenDispute(e.tx(carol), "car", "mine"), "only the current or a prior owner of asset car may open a dispute")
+	wantErr(t, e.assets.OpenDispute(e.tx(alice), "car", " "), "claim is required")
+	ctx := e.tx(alice)
+	mustOK(t, e.assets.OpenDispute(ctx, "car", "the transfer to bob was never paid"))
+	var opened Dispute
+	if name := eventOf(t, ctx, &opened); name != "DisputeOpened" || opened.Respondent != bob.id() || opened.Claimant != alice.id() {
+		t.Fatalf("event %s %+v", name, opened)
+	}
+	wantErr(t, e.assets.OpenDispute(e.tx(bob), "car", "again"), "asset car is disputed by")
+
+	// An open dispute blocks every way of changing the owner.
+	err := e.assets.UpdateAssetOwner(e.tx(bob), "car", carol.id())
+	if !errors.Is(err, errOpenDispute) {
+		t.Fatalf("err = %v, want errOpenDispute", err)
+	}
+	wantErr(t, e.nft.TransferFrom(e.tx(bob), bob.id(), carol.id(), "car"), "asset car is disputed")
+	wantErr(t, e.assets.DeleteAsset(e.tx(bob), "car"), "asset car is disputed")
+
+	digest := strings.Repeat("ab", 32)
+	mustOK(t, e.assets.SubmitEvidence(e.tx(alice), "car", "bank statement", strings.ToUpper(digest)))
+	mustOK(t, e.assets.SubmitEvidence(e.tx(bob), "car", "signed receipt", ""))
+	mustOK(t, e.assets.SubmitEvidence(e.tx(regulator), "car", "hearing scheduled", ""))
+	wantErr(t, e.assets.SubmitEvidence(e.tx(carol), "car", "hearsay", ""), "only the parties to the dispute on asset car or an arbitrator")
+	wantErr(t, e.assets.SubmitEvidence(e.tx(alice), "car", " ", ""), "statement is required")
+	wantErr(t, e.assets.SubmitEvidence(e.tx(alice), "car", "x", "abc"), "sha256 must be 64 hex characters")
+	mustOK(t, e.assets.FreezeAsset(e.tx(regulator), "car", "CASE-4"))
+	wantErr(t, e.assets.SubmitEvidence(e.tx(bob), "car", "more", ""), "frozen under case CASE-4")
+	mustOK(t, e.assets.UnfreezeAsset(e.tx(regulator), "car"))
+
+	trail, err := e.assets.GetAuditTrail(e.tx(carol), "car", 20, "")
+	mustOK(t, err)
+	// The last evidence precedes the freeze and unfreeze records.
+	last := trail.Records[len(trail.Records)-3]
+	if last.Function != "SubmitEvidence" || len(last.Changes) != 1 || !strings.Contains(last.Changes[0].After, "hearing scheduled") || strings.Contains(last.Changes[0].Before, "hearing scheduled") {
+		t.Fatalf("evidence audit record %+v", last)
+	}
+
+	wantErr(t, e.assets.ResolveDispute(e.tx(alice), "car", "alice wins", alice.id()), "only Org3MSP identities may resolve disputes")
+	wantErr(t, e.assets.ResolveDispute(e.tx(regulator), "car", " ", ""), "decision is required")
+	wantErr(t, e.assets.ResolveDispute(e.tx(regulator), "car", "carol wins", carol.id()), "is not a prior owner of asset car")
+	wantErr(t, e.assets.ResolveDispute(e.tx(regulator), "car", "bob keeps it", bob.id()), "already owns asset car")
+
+	ctx = e.tx(regulator)
+	mustOK(t, e.assets.ResolveDispute(ctx, "car", "payment not proven", alice.id()))
+	var resolved Dispute
+	if name := eventOf(t, ctx, &resolved); name != "DisputeResolved" || resolved.RestoredOwner != alice.id() {
+		t.Fatalf("event %s %+v", name, resolved)
+	}
+	if got := e.readAsset("car").Owner; got != alice.id() {
+		t.Fatalf("owner = %s, want alice", got)
+	}
+
+	disputes, err := e.assets.GetDisputes(e.tx(carol), "car")
+	mustOK(t, err)
+	if len(disputes) != 1 {
+		t.Fatalf("got %d disputes, want 1", len(disputes))
+	}
+	d := disputes[0]
+	if d.Status != DisputeResolved || d.Decision != "payment not proven" || d.ResolvedBy != regulator.id() || len(d.Evidence) != 3 || d.Evidence[0].SHA256 != digest {
+		t.Fatalf("unexpected dispute %+v", d)
+	}
+	wantErr(t, e.assets.SubmitEvidence(e.tx(alice), "car", "late", ""), "asset car has no open dispute")
+
+	mustOK(t, e.assets.UpdateAssetOwner(e.tx(alice), "car", carol.id()))
+}
+
+func TestDisputeDismissed(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("bike", "Alice", "300 EUR")
+	e.createAsset("frame", "Alice", "100 EUR")
+	e.verifyOwners("Bob")
+	e.assets.ArbitratorMSP = "Org2MSP"
+
+	// Label owners carry no identity, so anyone may dispute them.
+	mustOK(t, e.assets.OpenDispute(e.tx(carol), "frame", "stolen"))
+	wantErr(t, e.assets.AttachComponent(e.tx(alice), "bike", "frame"), "asset frame is disputed")
+	mustOK(t, e.assets.SubmitEvidence(e.tx(carol), "frame", "police report", ""))
+	wantErr(t, e.assets.ResolveDispute(e.tx(regulator), "frame", "dismissed", ""), "only Org2MSP identities may resolve disputes")
+	mustOK(t, e.assets.ResolveDispute(e.tx(bob), "frame", "dismissed", ""))
+
+	if got := e.readAsset("frame").Owner; got != "Alice" {
+		t.Fatalf("owner = %s", got)
+	}
+	mustOK(t, e.assets.AttachComponent(e.tx(alice), "bike", "frame"))
+	wantErr(t, e.assets.OpenDispute(e.tx(carol), "frame", "stolen"), "dispute the parent asset instead")
+	wantErr(t, e.assets.ResolveDispute(e.tx(bob), "bike", "none", ""), "asset bike has no open dispute")
+
+	_, err := e.assets.GetDisputes(e.tx(carol), "")
+	wantErr(t, err, "assetId is required")
+}
+
+func TestDisputePriorLabelOwner(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("boat", "Alice", "5000 EUR")
+	e.verifyOwners(alice.id())
+	mustOK(t, e.assets.UpdateAssetOwner(e.tx(bob), "boat", alice.id()))
+
+	// A prior label owner does not open the asset to everyone.
+	wantErr(t, e.assets.OpenDispute(e.tx(carol), "boat", "stolen"), "only the current or a prior owner of asset boat may open a dispute")
+	mustOK(t, e.assets.OpenDispute(e.tx(alice), "boat", "sold without consent"))
+}
+
+func TestOwnerHistorySinceLastCreation(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("asset1", "Alice", "10 EUR")
+	e.verifyOwners("Bob", "Carol")
+	mustOK(t, e.assets.UpdateAssetOwner(e.tx(alice), "asset1", "Bob"))
+	mustOK(t, e.assets.DeleteAsset(e.tx(alice), "asset1"))
+	e.createAsset("asset1", "Carol", "10 EUR")
+	mustOK(t, e.assets.UpdateAssetOwner(e.tx(alice), "asset1", "Alice"))
+
+	owners, err := ownerHistory(e.tx(alice), "asset1")
+	mustOK(t, err)
+	if !equalIDs(owners, []string{"Carol", "Alice"}) {
+		t.Fatalf("owners = %v", owners)
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const documentObjectType = "document"
+
+// DocumentAnchor records the SHA-256 digest of an off-chain document, such as
+// an invoice or a title deed, attached to an asset. The document itself stays
+// off-chain; URI optionally says where to fetch it.
+type DocumentAnchor struct {
+	AssetID    string `json:"assetId"`
+	DocType    string `json:"docType"`
+	SHA256     string `json:"sha256"`
+	URI        string `json:"uri,omitempty" metadata:",optional"`
+	AnchoredBy string `json:"anchoredBy"`
+	AnchoredAt string `json:"anchoredAt"`
+	TxID       string `json:"txId"`
+}
+
+// AnchorDocument attaches the SHA-256 digest of a document to an asset. Each
+// digest can be anchored to an asset once. Only the owner of the asset or an
+// operator holding the updateValue right may anchor documents.
+func (c *AssetContract) AnchorDocument(ctx contractapi.TransactionContextInterface, assetID string, docType string, sha256 string, uri string) error {
+	guard, err := beginRequest(ctx, "AnchorDocument", assetID, docType, sha256, uri)
+	if err != nil {
+		return err
+	}
+	if _, ok := guard.replayed(); ok {
+		return nil
+	}
+
+	if err := requireOwnerMSP(ctx); err != nil {
+		return err
+	}
+
+	docType = strings.TrimSpace(docType)
+	if docType == "" {
+		return errors.New("docType is required")
+	}
+	digest, err := normalizeSHA256(sha256)
+	if err != nil {
+		return err
+	}
+
+	asset, err := c.ReadAsset(ctx, assetID)
+	if err != nil {
+		return err
+	}
+	a, err := authorize(ctx, asset, RightUpdateValue)
+	if err != nil {
+		return err
+	}
+
+	existing, err := readDocument(ctx, asset.ID, digest)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("document %s is already anchored to asset %s in tx %s", digest, asset.ID, existing.TxID)
+	}
+
+	now, err := txTimeRFC3339(ctx)
+	if err != nil {
+		return err
+	}
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("get msp id: %w", err)
+	}
+
+	anchor := DocumentAnchor{
+		AssetID:    asset.ID,
+		DocType:    docType,
+		SHA256:     digest,
+		URI:        strings.TrimSpace(uri),
+		AnchoredBy: mspID,
+		AnchoredAt: now,
+		TxID:       ctx.GetStub().GetTxID(),
+	}
+	b, err := json.Marshal(anchor)
+	if err != nil {
+		return fmt.Errorf("marshal document anchor: %w", err)
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(documentObjectType, []string{asset.ID, digest})
+	if err != nil {
+		return fmt.Errorf("create document key: %w", err)
+	}
+	if err := ctx.GetStub().PutState(key, b); err != nil {
+		return fmt.Errorf("put state: %w", err)
+	}
+	if err := recordRelatedAudit(ctx, "AnchorDocument", asset.ID, "document", nil, anchor); err != nil {
+		return err
+	}
+	if err := emitOperation(ctx, "AnchorDocument", asset, asset.Owner, a); err != nil {
+		return err
+	}
+	return guard.complete(ctx, "")
+}
+
+// VerifyDocument returns the anchor of a document digest, or an error when the
+// digest was never anchored to the asset.
+func (c *AssetContract) VerifyDocument(ctx contractapi.TransactionContextInterface, assetID string, sha256 string) (*DocumentAnchor, error) {
+	digest, err := normalizeSHA256(sha256)
+	if err != nil {
+		return nil, err
+	}
+	assetID, err = existingID("assetId", assetID)
+	if err != nil {
+		return nil, err
+	}
+
+	anchor, err := readDocument(ctx, assetID, digest)
+	if err != nil {
+		return nil, err
+	}
+	if anchor == nil {
+		return nil, fmt.Errorf("document %s is not anchored to asset %s", digest, assetID)
+	}
+	return anchor, nil
+}
+
+// GetDocuments returns the documents anchored to an asset, ordered by digest.
+func (c *AssetContract) GetDocuments(ctx contractapi.TransactionContextInterface, assetID string) ([]*DocumentAnchor, error) {
+	assetID, err := existingID("assetId", assetID)
+	if err != nil {
+		return nil, err
+	}
+
+	iter, err := ctx.GetStub().GetStateByPartialCompositeKey(documentObjectType, []string{assetID})
+	if err != nil {
+		return nil, fmt.Errorf("document query: %w", err)
+	}
+	defer iter.Close()
+
+	out := []*DocumentAnchor{}
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return nil, fmt.Errorf("iter next: %w", err)
+		}
+		var anchor DocumentAnchor
+		if err := json.Unmarshal(kv.Value, &anchor); err != nil {
+			return nil, fmt.Errorf("unmarshal document anchor: %w", err)
+		}
+		out = append(out, &anchor)
+	}
+	return out, nil
+}
+
+func readDocument(ctx contractapi.TransactionContextInterface, assetID string, digest string) (*DocumentAnchor, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(documentObjectType, []string{assetID, digest})
+	if err != nil {
+		return nil, fmt.Errorf("create document key: %w", err)
+	}
+	b, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("get state: %w", err)
+	}
+	if b == nil {
+		return nil, nil
+	}
+	var anchor DocumentAnchor
+	if err := json.Unmarshal(b, &anchor); err != nil {
+		return nil, fmt.Errorf("unmarshal document anchor: %w", err)
+	}
+	return &anchor, nil
+}
+
+// normalizeSHA256 checks that s is a hex encoded SHA-256 digest and returns it
+// in lower case.
+func normalizeSHA256(s string) (string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if b, err := hex.DecodeString(s); err != nil || len(b) != 32 {
+		return "", fmt.Errorf("sha256 must be 64 hex characters, got %q", s)
+	}
+	return s, nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAnchorDocument(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("asset1", "Alice", "10 EUR")
+	digest := strings.Repeat("ab", 32)
+
+	mustOK(t, e.assets.AnchorDocument(e.tx(bob), "asset1", "invoice", strings.ToUpper(digest), "https://docs.example.com/1"))
+	wantErr(t, e.assets.AnchorDocument(e.tx(bob), "asset1", "invoice", digest, ""), "already anchored")
+	wantErr(t, e.assets.AnchorDocument(e.tx(bob), "asset1", " ", digest, ""), "docType is required")
+	wantErr(t, e.assets.AnchorDocument(e.tx(bob), "asset1", "invoice", "abc", ""), "sha256 must be 64 hex characters")
+	wantErr(t, e.assets.AnchorDocument(e.tx(bob), "missing", "invoice", digest, ""), "not found")
+
+	anchor, err := e.assets.VerifyDocument(e.tx(carol), "asset1", digest)
+	mustOK(t, err)
+	if anchor.DocType != "invoice" || anchor.AnchoredBy != "Org2MSP" || anchor.URI != "https://docs.example.com/1" {
+		t.Fatalf("unexpected anchor %+v", anchor)
+	}
+	_, err = e.assets.VerifyDocument(e.tx(carol), "asset1", strings.Repeat("cd", 32))
+	wantErr(t, err, "is not anchored to asset asset1")
+
+	mustOK(t, e.assets.AnchorDocument(e.tx(bob), "asset1", "contract", strings.Repeat("cd", 32), ""))
+	docs, err := e.assets.GetDocuments(e.tx(carol), "asset1")
+	mustOK(t, err)
+	if len(docs) != 2 {
+		t.Fatalf("got %d documents, want 2", len(docs))
+	}
+}
+
+func TestAnchorDocumentRequiresOwner(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("asset1", alice.id(), "10 EUR")
+	digest := strings.Repeat("ab", 32)
+
+	wantErr(t, e.assets.AnchorDocument(e.tx(bob), "asset1", "invoice", digest, ""), "only the owner of asset asset1 or an operator with the updateValue right may do this")
+	mustOK(t, e.assets.GrantOperator(e.tx(alice), bob.id(), "asset1", []string{RightUpdateValue}, ""))
+	ctx := e.tx(bob)
+	mustOK(t, e.assets.AnchorDocument(ctx, "asset1", "invoice", digest, ""))
+	var event OperationEvent
+	if name := eventOf(t, ctx, &event); name != "AssetOperation" || event.Actor != bob.id() || !event.Operator || event.Owner != alice.id() {
+		t.Fatalf("event %s %+v", name, event)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// PurgeEvent reports an erasure to the compliance log. It never carries the
+// erased data.
+type PurgeEvent struct {
+	Owner      string `json:"owner"`
+	Collection string `json:"collection,omitempty" metadata:",optional"`
+	Reason     string `json:"reason"`
+	ErasedBy   string `json:"erasedBy"`
+	ErasedAt   string `json:"erasedAt"`
+	TxID       string `json:"txId"`
+}
+
+// ErasePersonalData honors an erasure request for an owner. The personal
+// data is purged with PurgePrivateData, which removes it and its history
+// from the private stores of all peers, and the public profile is replaced by
+// a tombstone with the erased KYC status. reason, e.g. the reference of the
+// request, is reported in a PersonalDataPurged event and must not contain
+// personal data itself. Only admins of the compliance org may erase; the
+// compliance org is a member of every personal data collection, so its peers
+// can endorse the purge.
+func (r *OwnerRegistryContract) ErasePersonalData(ctx contractapi.TransactionContextInterface, owner string, reason string) error {
+	guard, err := beginRequest(ctx, "ErasePersonalData", owner, reason)
+	if err != nil {
+		return err
+	}
+	if _, ok := guard.replayed(); ok {
+		return nil
+	}
+
+	if err := r.requireCompliance(ctx); err != nil {
+		return err
+	}
+	if err := requireOrgAdmin(ctx); err != nil {
+		return err
+	}
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return errors.New("reason is required")
+	}
+
+	profile, err := r.GetOwnerProfile(ctx, owner)
+	if err != nil {
+		return err
+	}
+	if profile.KYCStatus == KYCErased {
+		return fmt.Errorf("owner %s has already been erased", profile.Owner)
+	}
+
+	if profile.DataCollection != "" {
+		key, err := ctx.GetStub().CreateCompositeKey(personalDataObjectType, []string{profile.Owner})
+		if err != nil {
+			return fmt.Errorf("create personal data key: %w", err)
+		}
+		if err := ctx.GetStub().PurgePrivateData(profile.DataCollection, key); err != nil {
+			return fmt.Errorf("purge private data: %w", err)
+		}
+	}
+
+	now, err := txTimeRFC3339(ctx)
+	if err != nil {
+		return err
+	}
+	erasedBy, err := submitterID(ctx)
+	if err != nil {
+		return err
+	}
+	tombstone := &OwnerProfile{
+		Owner:          profile.Owner,
+		KYCStatus:      KYCErased,
+		RegisteredBy:   profile.RegisteredBy,
+		RegisteredAt:   profile.RegisteredAt,
+		DataCollection: profile.DataCollection,
+		ErasedBy:       erasedBy,
+		ErasedAt:       now,
+	}
+	if err := putOwnerProfile(ctx, tombstone); err != nil {
+		return err
+	}
+	if err := setEvent(ctx, "PersonalDataPurged", PurgeEvent{
+		Owner:      profile.Owner,
+		Collection: profile.DataCollection,
+		Reason:     reason,
+		ErasedBy:   erasedBy,
+		ErasedAt:   now,
+		TxID:       ctx.GetStub().GetTxID(),
+	}); err != nil {
+		return err
+	}
+	return guard.complete(ctx, "")
+}
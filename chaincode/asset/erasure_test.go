@@ -0,0 +1,80 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestErasePersonalData(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("asset1", "Alice", "10 EUR")
+	e.verifyOwners("Bob")
+
+	profile, err := e.registry.GetOwnerProfile(e.tx(bob), "Alice")
+	mustOK(t, err)
+	if profile.DataCollection != "personalDataOrg1MSP" {
+		t.Fatalf("collection = %s", profile.DataCollection)
+	}
+	personal, err := e.registry.GetOwnerPersonalData(e.tx(alice), "Alice")
+	mustOK(t, err)
+	if personal.Name != "Owner Alice" {
+		t.Fatalf("personal data %+v", personal)
+	}
+
+	wantErr(t, e.registry.ErasePersonalData(e.tx(org1Admin), "Alice", "GDPR-17"), "only Org3MSP identities may set KYC statuses")
+	wantErr(t, e.registry.ErasePersonalData(e.tx(regulator), "Alice", "GDPR-17"), "only org admin identities may do this")
+	wantErr(t, e.registry.ErasePersonalData(e.tx(org3Admin), "Alice", " "), "reason is required")
+	wantErr(t, e.registry.ErasePersonalData(e.tx(org3Admin), "Carol", "GDPR-17"), "owner Carol is not registered")
+
+	ctx := e.tx(org3Admin)
+	mustOK(t, e.registry.ErasePersonalData(ctx, "Alice", "GDPR-17"))
+	var event PurgeEvent
+	if name := eventOf(t, ctx, &event); name != "PersonalDataPurged" || event.Owner != "Alice" || event.Reason != "GDPR-17" || event.ErasedBy != org3Admin.id() || event.Collection != "personalDataOrg1MSP" {
+		t.Fatalf("event %s %+v", name, event)
+	}
+	key, _ := ctx.stub.CreateCompositeKey(personalDataObjectType, []string{"Alice"})
+	e.ledger.commit()
+	if len(e.ledger.purged) != 1 || e.ledger.purged[0] != "personalDataOrg1MSP/"+key {
+		t.Fatalf("purged = %q", e.ledger.purged)
+	}
+
+	tombstone, err := e.registry.GetOwnerProfile(e.tx(bob), "Alice")
+	mustOK(t, err)
+	if tombstone.KYCStatus != KYCErased || tombstone.Jurisdiction != "" || tombstone.Note != "" || tombstone.ErasedBy != org3Admin.id() || tombstone.RegisteredBy != alice.id() {
+		t.Fatalf("tombstone %+v", tombstone)
+	}
+	_, err = e.registry.GetOwnerPersonalData(e.tx(alice), "Alice")
+	wantErr(t, err, "owner Alice has been erased")
+	wantErr(t, e.registry.ErasePersonalData(e.tx(org3Admin), "Alice", "GDPR-17"), "owner Alice has already been erased")
+	wantErr(t, e.registry.SetKYCStatus(e.tx(regulator), "Alice", KYCVerified, ""), "owner Alice has been erased")
+
+	// Erased owners can no longer receive assets.
+	mustOK(t, e.assets.UpdateAssetOwner(e.tx(alice), "asset1", "Bob"))
+	wantErr(t, e.assets.UpdateAssetOwner(e.tx(bob), "asset1", "Alice"), "owner Alice has KYC status erased")
+}
+
+func TestEraseOwnerRegisteredByOtherOrg(t *testing.T) {
+	e := newTestEnv(t)
+	mustOK(t, e.registerOwner(bob, "Bob", "Bob Example", "US"))
+
+	// The compliance org shares the collection of the registering org, so it
+	// can read and purge the data, while other orgs cannot.
+	profile, err := e.registry.GetOwnerProfile(e.tx(regulator), "Bob")
+	mustOK(t, err)
+	if profile.Name != "Bob Example" || profile.DataCollection != "personalDataOrg2MSP" {
+		t.Fatalf("unexpected profile %+v", profile)
+	}
+	if profile, err = e.registry.GetOwnerProfile(e.tx(alice), "Bob"); err != nil || profile.Name != "" {
+		t.Fatalf("name of Bob visible to Org1: %+v, %v", profile, err)
+	}
+	key, _ := e.tx(alice).stub.CreateCompositeKey(personalDataObjectType, []string{"Bob"})
+	wantErr(t, e.tx(alice).stub.PurgePrivateData(profile.DataCollection, key), "does not have write access")
+
+	mustOK(t, e.registry.ErasePersonalData(e.tx(org3Admin), "Bob", "GDPR-18"))
+	e.ledger.commit()
+	if len(e.ledger.purged) != 1 || e.ledger.purged[0] != "personalDataOrg2MSP/"+key {
+		t.Fatalf("purged = %q", e.ledger.purged)
+	}
+	if _, ok := e.ledger.private["personalDataOrg2MSP"][key]; ok {
+		t.Fatal("personal data kept after purge")
+	}
+}
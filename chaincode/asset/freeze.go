@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	freezeObjectType    = "freeze"
+	defaultRegulatorMSP = "Org3MSP"
+)
+
+var errComplianceHold = errors.New("compliance hold")
+
+type FreezeRecord struct {
+	AssetID  string `json:"assetId"`
+	CaseRef  string `json:"caseRef"`
+	FrozenBy string `json:"frozenBy"`
+	FrozenAt string `json:"frozenAt"`
+	TxID     string `json:"txId"`
+}
+
+func (c *AssetContract) FreezeAsset(ctx contractapi.TransactionContextInterface, id string, caseRef string) error {
+	guard, err := beginRequest(ctx, "FreezeAsset", id, caseRef)
+	if err != nil {
+		return err
+	}
+	if _, ok := guard.replayed(); ok {
+		return nil
+	}
+
+	if err := c.requireRegulator(ctx); err != nil {
+		return err
+	}
+	caseRef = strings.TrimSpace(caseRef)
+	if caseRef == "" {
+		return errors.New("caseRef is required")
+	}
+
+	asset, err := c.ReadAsset(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	existing, err := readFreeze(ctx, asset.ID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("asset %s is already frozen under case %s", asset.ID, existing.CaseRef)
+	}
+
+	now, err := txTimeRFC3339(ctx)
+	if err != nil {
+		return err
+	}
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("get msp id: %w", err)
+	}
+
+	rec := FreezeRecord{
+		AssetID:  asset.ID,
+		CaseRef:  caseRef,
+		FrozenBy: mspID,
+		FrozenAt: now,
+		TxID:     ctx.GetStub().GetTxID(),
+	}
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("marshal freeze record: %w", err)
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(freezeObjectType, []string{asset.ID})
+	if err != nil {
+		return fmt.Errorf("create freeze key: %w", err)
+	}
+	if err := ctx.GetStub().PutState(key, b); err != nil {
+		return fmt.Errorf("put state: %w", err)
+	}
+	if err := recordRelatedAudit(ctx, "FreezeAsset", asset.ID, "freeze", nil, rec); err != nil {
+		return err
+	}
+	return guard.complete(ctx, "")
+}
+
+func (c *AssetContract) UnfreezeAsset(ctx contractapi.TransactionContextInterface, id string) error {
+	guard, err := beginRequest(ctx, "UnfreezeAsset", id)
+	if err != nil {
+		return err
+	}
+	if _, ok := guard.replayed(); ok {
+		return nil
+	}
+
+	if err := c.requireRegulator(ctx); err != nil {
+		return err
+	}
+
+	asset, err := c.ReadAsset(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	existing, err := readFreeze(ctx, asset.ID)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return fmt.Errorf("asset %s is not frozen", asset.ID)
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(freezeObjectType, []string{asset.ID})
+	if err != nil {
+		return fmt.Errorf("create freeze key: %w", err)
+	}
+	if err := ctx.GetStub().DelState(key); err != nil {
+		return fmt.Errorf("delete state: %w", err)
+	}
+	if err := recordRelatedAudit(ctx, "UnfreezeAsset", asset.ID, "freeze", existing, nil); err != nil {
+		return err
+	}
+	return guard.complete(ctx, "")
+}
+
+func (c *AssetContract) ListFrozenAssets(ctx contractapi.TransactionContextInterface) ([]*FreezeRecord, error) {
+	iter, err := ctx.GetStub().GetStateByPartialCompositeKey(freezeObjectType, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("freeze query: %w", err)
+	}
+	defer iter.Close()
+
+	out := []*FreezeRecord{}
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return nil, fmt.Errorf("iter next: %w", err)
+		}
+		var rec FreezeRecord
+		if err := json.Unmarshal(kv.Value, &rec); err != nil {
+			return nil, fmt.Errorf("unmarshal freeze record: %w", err)
+		}
+		out = append(out, &rec)
+	}
+	return out, nil
+}
+
+// requireRegulator rejects invokers outside the configured regulator MSP.
+func (c *AssetContract) requireRegulator(ctx contractapi.TransactionContextInterface) error {
+	regulator := c.RegulatorMSP
+	if regulator == "" {
+		regulator = defaultRegulatorMSP
+	}
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("get msp id: %w", err)
+	}
+	if mspID != regulator {
+		return fmt.Errorf("only %s identities may manage compliance holds", regulator)
+	}
+	return nil
+}
+
+// checkNotFrozen fails with errComplianceHold while the asset is frozen.
+func checkNotFrozen(ctx contractapi.TransactionContextInterface, id string) error {
+	rec, err := readFreeze(ctx, id)
+	if err != nil {
+		return err
+	}
+	if rec != nil {
+		return fmt.Errorf("%w: asset %s is frozen under case %s", errComplianceHold, id, rec.CaseRef)
+	}
+	return nil
+}
+
+func readFreeze(ctx contractapi.TransactionContextInterface, id string) (*FreezeRecord, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(freezeObjectType, []string{id})
+	if err != nil {
+		return nil, fmt.Errorf("create freeze key: %w", err)
+	}
+	b, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("get state: %w", err)
+	}
+	if b == nil {
+		return nil, nil
+	}
+	var rec FreezeRecord
+	if err := json.Unmarshal(b, &rec); err != nil {
+		return nil, fmt.Errorf("unmarshal freeze record: %w", err)
+	}
+	return &rec, nil
+}
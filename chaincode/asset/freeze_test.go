@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFreezeAsset(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("asset1", "Alice", "10 EUR")
+	e.verifyOwners("Bob")
+
+	wantErr(t, e.assets.FreezeAsset(e.tx(alice), "asset1", "CASE-1"), "only Org3MSP identities")
+	wantErr(t, e.assets.FreezeAsset(e.tx(regulator), "asset1", " "), "caseRef is required")
+	wantErr(t, e.assets.FreezeAsset(e.tx(regulator), "missing", "CASE-1"), "not found")
+
+	mustOK(t, e.assets.FreezeAsset(e.tx(regulator), "asset1", "CASE-1"))
+	wantErr(t, e.assets.FreezeAsset(e.tx(regulator), "asset1", "CASE-2"), "already frozen under case CASE-1")
+
+	err := e.assets.UpdateAssetOwner(e.tx(alice), "asset1", "Bob")
+	if !errors.Is(err, errComplianceHold) {
+		t.Fatalf("UpdateAssetOwner error = %v, want compliance hold", err)
+	}
+	wantErr(t, e.assets.UpdateAssetValue(e.tx(alice), "asset1", "1 EUR"), "frozen under case CASE-1")
+	wantErr(t, e.assets.DeleteAsset(e.tx(alice), "asset1"), "frozen under case CASE-1")
+
+	frozen, err := e.assets.ListFrozenAssets(e.tx(bob))
+	mustOK(t, err)
+	if len(frozen) != 1 || frozen[0].AssetID != "asset1" || frozen[0].FrozenBy != "Org3MSP" {
+		t.Fatalf("unexpected frozen list %+v", frozen)
+	}
+
+	wantErr(t, e.assets.UnfreezeAsset(e.tx(alice), "asset1"), "only Org3MSP identities")
+	mustOK(t, e.assets.UnfreezeAsset(e.tx(regulator), "asset1"))
+	wantErr(t, e.assets.UnfreezeAsset(e.tx(regulator), "asset1"), "asset asset1 is not frozen")
+	mustOK(t, e.assets.UpdateAssetOwner(e.tx(alice), "asset1", "Bob"))
+}
+
+func TestFreezeAssetCustomRegulator(t *testing.T) {
+	e := newTestEnv(t)
+	e.assets.RegulatorMSP = "Org2MSP"
+	e.createAsset("asset1", "Alice", "10 EUR")
+
+	wantErr(t, e.assets.FreezeAsset(e.tx(regulator), "asset1", "CASE-1"), "only Org2MSP identities")
+	mustOK(t, e.assets.FreezeAsset(e.tx(bob), "asset1", "CASE-1"))
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ReadAssetAsOf returns the asset as it was at timestamp (RFC 3339), rebuilt
+// from the key history. It fails if the asset did not exist yet or was
+// deleted at that time.
+func (c *AssetContract) ReadAssetAsOf(ctx contractapi.TransactionContextInterface, id string, timestamp string) (*Asset, error) {
+	id, err := existingID("id", id)
+	if err != nil {
+		return nil, err
+	}
+	asOf, err := time.Parse(time.RFC3339Nano, timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timestamp: %w", err)
+	}
+
+	iter, err := ctx.GetStub().GetHistoryForKey(id)
+	if err != nil {
+		return nil, fmt.Errorf("history query: %w", err)
+	}
+	defer iter.Close()
+
+	// The order of history entries differs between Fabric releases, so pick
+	// the latest modification at or before asOf explicitly.
+	var (
+		latest   time.Time
+		value    []byte
+		deleted  bool
+		found    bool
+		modified string
+	)
+	for iter.HasNext() {
+		mod, err := iter.Next()
+		if err != nil {
+			return nil, fmt.Errorf("iter next: %w", err)
+		}
+		if mod.Timestamp == nil {
+			continue
+		}
+		at := time.Unix(mod.Timestamp.Seconds, int64(mod.Timestamp.Nanos)).UTC()
+		if at.After(asOf) || (found && at.Before(latest)) {
+			continue
+		}
+		latest, value, deleted, found = at, mod.Value, mod.IsDelete, true
+		modified = mod.TxId
+	}
+
+	if !found {
+		return nil, fmt.Errorf("asset %s did not exist at %s", id, timestamp)
+	}
+	if deleted {
+		return nil, fmt.Errorf("asset %s was deleted at %s by transaction %s", id, latest.Format(time.RFC3339Nano), modified)
+	}
+
+	var asset Asset
+	if err := json.Unmarshal(value, &asset); err != nil {
+		return nil, fmt.Errorf("unmarshal asset: %w", err)
+	}
+	return &asset, nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReadAssetAsOf(t *testing.T) {
+	e := newTestEnv(t)
+	before := e.ledger.clock.Format(time.RFC3339Nano)
+	e.createAsset("asset1", "Alice", "10 EUR")
+	created := e.ledger.clock.Format(time.RFC3339Nano)
+	mustOK(t, e.assets.UpdateAssetValue(e.tx(alice), "asset1", "20 EUR"))
+	updated := e.ledger.clock.Format(time.RFC3339Nano)
+	mustOK(t, e.assets.DeleteAsset(e.tx(alice), "asset1"))
+	deleted := e.ledger.clock.Format(time.RFC3339Nano)
+	e.createAsset("asset1", "Alice", "30 EUR")
+
+	for ts, amount := range map[string]int64{created: 1000, updated: 2000} {
+		asset, err := e.assets.ReadAssetAsOf(e.tx(bob), "asset1", ts)
+		mustOK(t, err)
+		if asset.Value.Amount != amount {
+			t.Errorf("as of %s: amount = %d, want %d", ts, asset.Value.Amount, amount)
+		}
+	}
+
+	_, err := e.assets.ReadAssetAsOf(e.tx(bob), "asset1", before)
+	wantErr(t, err, "did not exist at")
+	_, err = e.assets.ReadAssetAsOf(e.tx(bob), "asset1", deleted)
+	wantErr(t, err, "asset asset1 was deleted at")
+	_, err = e.assets.ReadAssetAsOf(e.tx(bob), "asset1", "yesterday")
+	wantErr(t, err, "invalid timestamp")
+	_, err = e.assets.ReadAssetAsOf(e.tx(bob), " ", created)
+	wantErr(t, err, "id is required")
+
+	current, err := e.assets.ReadAssetAsOf(e.tx(bob), "asset1", e.ledger.clock.Add(time.Hour).Format(time.RFC3339))
+	mustOK(t, err)
+	if current.Value.Amount != 3000 {
+		t.Fatalf("current amount = %d", current.Value.Amount)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	requestObjectType = "request"
+	// requestIDKey is the transient data key carrying the client request ID.
+	requestIDKey = "requestId"
+)
+
+type requestRecord struct {
+	RequestID string `json:"requestId"`
+	Function  string `json:"function"`
+	ArgsHash  string `json:"argsHash"`
+	Result    string `json:"result"`
+	TxID      string `json:"txId"`
+}
+
+// requestGuard deduplicates retries of a mutating call. Clients pass the same
+// request ID in transient data on every attempt; once an attempt has
+// committed, later attempts replay its result instead of running again.
+type requestGuard struct {
+	key       string
+	requestID string
+	function  string
+	argsHash  string
+	previous  *requestRecord
+}
+
+// beginRequest returns a guard for the current call. Without a request ID in
+// transient data the guard is inert and the call always runs.
+func beginRequest(ctx contractapi.TransactionContextInterface, function string, args ...string) (*requestGuard, error) {
+	transient, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return nil, fmt.Errorf("get transient: %w", err)
+	}
+	requestID := strings.TrimSpace(string(transient[requestIDKey]))
+	if requestID == "" {
+		return &requestGuard{}, nil
+	}
+
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("get msp id: %w", err)
+	}
+	submitter, err := submitterID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Scope request IDs per submitter. Replays skip authorization, so an
+	// identity may only replay its own requests.
+	key, err := ctx.GetStub().CreateCompositeKey(requestObjectType, []string{mspID, submitter, requestID})
+	if err != nil {
+		return nil, fmt.Errorf("create request key: %w", err)
+	}
+
+	sum := sha256.Sum256([]byte(function + "\x00" + strings.Join(args, "\x00")))
+	g := &requestGuard{
+		key:       key,
+		requestID: requestID,
+		function:  function,
+		argsHash:  hex.EncodeToString(sum[:]),
+	}
+
+	b, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("get state: %w", err)
+	}
+	if b == nil {
+		return g, nil
+	}
+
+	var rec requestRecord
+	if err := json.Unmarshal(b, &rec); err != nil {
+		return nil, fmt.Errorf("unmarshal request record: %w", err)
+	}
+	if rec.Function != g.function || rec.ArgsHash != g.argsHash {
+		return nil, fmt.Errorf("request %s was already used for a different %s call", requestID, rec.Function)
+	}
+	g.previous = &rec
+	return g, nil
+}
+
+// replayed reports whether the request already committed, and its result.
+func (g *requestGuard) replayed() (string, bool) {
+	if g.previous == nil {
+		return "", false
+	}
+	return g.previous.Result, true
+}
+
+// complete stores the result of the call under the request's dedupe key.
+func (g *requestGuard) complete(ctx contractapi.TransactionContextInterface, result string) error {
+	if g.key == "" {
+		return nil
+	}
+
+	rec := requestRecord{
+		RequestID: g.requestID,
+		Function:  g.function,
+		ArgsHash:  g.argsHash,
+		Result:    result,
+		TxID:      ctx.GetStub().GetTxID(),
+	}
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("marshal request record: %w", err)
+	}
+	return ctx.GetStub().PutState(g.key, b)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// adminOU is the node OU of org admin certificates, see the NodeOUs config
+// written by fabric-enroller.
+const adminOU = "admin"
+
+// submitterID returns the invoking identity as "x509::<subject>::<issuer>".
+// Features that bind assets to identities store owners in this form.
+func submitterID(ctx contractapi.TransactionContextInterface) (string, error) {
+	b64, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("get client id: %w", err)
+	}
+	id, err := base64.StdEncoding.DecodeString(b64)
+	if err != nil {
+		return "", fmt.Errorf("decode client id: %w", err)
+	}
+	return string(id), nil
+}
+
+// isIdentity reports whether owner is a client identity as returned by
+// submitterID rather than a plain label.
+func isIdentity(owner string) bool {
+	return strings.HasPrefix(owner, "x509::")
+}
+
+// requireOwner rejects invokers other than the identity owning the asset and
+// its operators holding right.
+func requireOwner(ctx contractapi.TransactionContextInterface, asset *Asset, right string) (*actor, error) {
+	id, err := submitterID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if asset.Owner == id {
+		return &actor{id: id}, nil
+	}
+	ok, err := actsFor(ctx, id, asset.Owner, asset.ID, right)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("only the owner of asset %s or an operator with the %s right may do this", asset.ID, right)
+	}
+	return &actor{id: id, operator: true}, nil
+}
+
+// requireOrgAdmin rejects invokers whose certificate lacks the admin node OU.
+func requireOrgAdmin(ctx contractapi.TransactionContextInterface) error {
+	cert, err := ctx.GetClientIdentity().GetX509Certificate()
+	if err != nil {
+		return fmt.Errorf("get client certificate: %w", err)
+	}
+	if cert != nil && contains(cert.Subject.OrganizationalUnit, adminOU) {
+		return nil
+	}
+	return errors.New("only org admin identities may do this")
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	updatedIndex   = "updated"
+	componentIndex = "component"
+	ownerIndex     = "owner"
+	holderIndex    = "holder"
+	tagIndex       = "tag"
+	valueIndex     = "value"
+	// valueAmountWidth zero-pads amounts in value index keys to the digits
+	// of the largest int64, so that keys sort numerically.
+	valueAmountWidth = 19
+	// indexTimeLayout is a fixed width UTC layout, so that index keys sort
+	// chronologically. RFC3339Nano drops trailing zeros and does not.
+	indexTimeLayout = "2006-01-02T15:04:05.000000000Z"
+)
+
+// indexValue is stored under index keys. Fabric treats an empty value as a
+// delete, so a single null byte is used instead.
+var indexValue = []byte{0x00}
+
+type AssetPage struct {
+	Assets   []*Asset `json:"assets"`
+	Bookmark string   `json:"bookmark"`
+	Count    int32    `json:"count"`
+}
+
+// GetAssetsUpdatedSince pages through assets whose last update is at or after
+// ts, oldest first. Pass an empty bookmark for the first page. A page with an
+// empty bookmark in the response is the last one.
+func (c *AssetContract) GetAssetsUpdatedSince(ctx contractapi.TransactionContextInterface, ts string, pageSize int32, bookmark string) (*AssetPage, error) {
+	if pageSize <= 0 {
+		return nil, errors.New("pageSize must be > 0")
+	}
+	since, err := indexTime(ts)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ts: %w", err)
+	}
+
+	// The peer resumes paginated range queries at the bookmark key, so the
+	// first page starts directly at the requested timestamp.
+	if bookmark == "" {
+		bookmark, err = ctx.GetStub().CreateCompositeKey(updatedIndex, []string{since})
+		if err != nil {
+			return nil, fmt.Errorf("create index key: %w", err)
+		}
+	}
+
+	iter, meta, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(updatedIndex, []string{}, pageSize, bookmark)
+	if err != nil {
+		return nil, fmt.Errorf("index query: %w", err)
+	}
+	defer iter.Close()
+
+	page := &AssetPage{Assets: []*Asset{}}
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return nil, fmt.Errorf("iter next: %w", err)
+		}
+		_, attrs, err := ctx.GetStub().SplitCompositeKey(kv.Key)
+		if err != nil {
+			return nil, fmt.Errorf("split index key: %w", err)
+		}
+		asset, err := readAsset(ctx, attrs[1])
+		if err != nil {
+			return nil, err
+		}
+		page.Assets = append(page.Assets, asset)
+	}
+	page.Bookmark = meta.GetBookmark()
+	page.Count = meta.GetFetchedRecordsCount()
+	return page, nil
+}
+
+// GetAssetsByTag pages through the assets carrying tag, ordered by ID.
+func (c *AssetContract) GetAssetsByTag(ctx contractapi.TransactionContextInterface, tag string, pageSize int32, bookmark string) (*AssetPage, error) {
+	if pageSize <= 0 {
+		return nil, errors.New("pageSize must be > 0")
+	}
+	tag = normalizeTag(tag)
+	if tag == "" {
+		return nil, errors.New("tag is required")
+	}
+
+	iter, meta, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(tagIndex, []string{tag}, pageSize, bookmark)
+	if err != nil {
+		return nil, fmt.Errorf("index query: %w", err)
+	}
+	defer iter.Close()
+
+	page := &AssetPage{Assets: []*Asset{}}
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return nil, fmt.Errorf("iter next: %w", err)
+		}
+		_, attrs, err := ctx.GetStub().SplitCompositeKey(kv.Key)
+		if err != nil {
+			return nil, fmt.Errorf("split index key: %w", err)
+		}
+		asset, err := readAsset(ctx, attrs[1])
+		if err != nil {
+			return nil, err
+		}
+		page.Assets = append(page.Assets, asset)
+	}
+	page.Bookmark = meta.GetBookmark()
+	page.Count = meta.GetFetchedRecordsCount()
+	return page, nil
+}
+
+// GetAssetsByValueRange pages through the assets whose value lies between
+// min and max inclusive, lowest first. Both bounds must be in the same
+// currency, e.g. "100 EUR" and "250.50 EUR". Concealed values are not
+// indexed. A page with an empty bookmark in the response is the last one.
+func (c *AssetContract) GetAssetsByValueRange(ctx contractapi.TransactionContextInterface, min string, max string, pageSize int32, bookmark string) (*AssetPage, error) {
+	if pageSize <= 0 {
+		return nil, errors.New("pageSize must be > 0")
+	}
+	lower, err := parseValue("min", min)
+	if err != nil {
+		return nil, err
+	}
+	upper, err := parseValue("max", max)
+	if err != nil {
+		return nil, err
+	}
+	if lower.Currency != upper.Currency {
+		return nil, fmt.Errorf("min and max must be in the same currency, got %s and %s", lower.Currency, upper.Currency)
+	}
+	if lower.Amount > upper.Amount {
+		return nil, errors.New("min must not be greater than max")
+	}
+
+	// As in GetAssetsUpdatedSince, the first page starts at the key of the
+	// lower bound.
+	if bookmark == "" {
+		bookmark, err = ctx.GetStub().CreateCompositeKey(valueIndex, []string{lower.Currency, indexAmount(lower.Amount)})
+		if err != nil {
+			return nil, fmt.Errorf("create index key: %w", err)
+		}
+	}
+
+	iter, meta, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(valueIndex, []string{lower.Currency}, pageSize, bookmark)
+	if err != nil {
+		return nil, fmt.Errorf("index query: %w", err)
+	}
+	defer iter.Close()
+
+	page := &AssetPage{Assets: []*Asset{}, Bookmark: meta.GetBookmark(), Count: meta.GetFetchedRecordsCount()}
+	upperKey := indexAmount(upper.Amount)
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return nil, fmt.Errorf("iter next: %w", err)
+		}
+		_, attrs, err := ctx.GetStub().SplitCompositeKey(kv.Key)
+		if err != nil {
+			return nil, fmt.Errorf("split index key: %w", err)
+		}
+		if attrs[1] > upperKey {
+			// Entries past the upper bound were fetched but are not part of
+			// the range.
+			page.Bookmark = ""
+			page.Count = int32(len(page.Assets))
+			break
+		}
+		asset, err := readAsset(ctx, attrs[2])
+		if err != nil {
+			return nil, err
+		}
+		page.Assets = append(page.Assets, asset)
+	}
+	return page, nil
+}
+
+// ReindexAssets writes the index entries of every asset. It backfills assets
+// stored before an index existed and is safe to run repeatedly. As it
+// rewrites every index entry, only org admins may run it.
+func (c *AssetContract) ReindexAssets(ctx contractapi.TransactionContextInterface) (int, error) {
+	if err := requireOrgAdmin(ctx); err != nil {
+		return 0, err
+	}
+	assets, err := c.GetAllAssets(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, asset := range assets {
+		if err := updateIndexes(ctx, nil, asset); err != nil {
+			return 0, err
+		}
+	}
+	return len(assets), nil
+}
+
+// updateIndexes replaces the index entries of before with those of after.
+// Either may be nil for creates and deletes.
+func updateIndexes(ctx contractapi.TransactionContextInterface, before, after *Asset) error {
+	oldKeys, err := assetIndexKeys(ctx, before)
+	if err != nil {
+		return err
+	}
+	newKeys, err := assetIndexKeys(ctx, after)
+	if err != nil {
+		return err
+	}
+
+	for key := range oldKeys {
+		if _, ok := newKeys[key]; ok {
+			continue
+		}
+		if err := ctx.GetStub().DelState(key); err != nil {
+			return fmt.Errorf("delete index entry: %w", err)
+		}
+	}
+	for key := range newKeys {
+		if _, ok := oldKeys[key]; ok {
+			continue
+		}
+		if err := ctx.GetStub().PutState(key, indexValue); err != nil {
+			return fmt.Errorf("put index entry: %w", err)
+		}
+	}
+	return nil
+}
+
+// assetIndexKeys returns every index key an asset is stored under.
+func assetIndexKeys(ctx contractapi.TransactionContextInterface, asset *Asset) (map[string]struct{}, error) {
+	keys := make(map[string]struct{})
+	if asset == nil {
+		return keys, nil
+	}
+
+	updated, err := indexTime(asset.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("asset %s: invalid updatedAt: %w", asset.ID, err)
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(updatedIndex, []string{updated, asset.ID})
+	if err != nil {
+		return nil, fmt.Errorf("create index key: %w", err)
+	}
+	keys[key] = struct{}{}
+
+	key, err = ctx.GetStub().CreateCompositeKey(ownerIndex, []string{asset.Owner, asset.ID})
+	if err != nil {
+		return nil, fmt.Errorf("create index key: %w", err)
+	}
+	keys[key] = struct{}{}
+
+	for _, share := range holdings(asset) {
+		key, err := ctx.GetStub().CreateCompositeKey(holderIndex, []string{share.Holder, asset.ID})
+		if err != nil {
+			return nil, fmt.Errorf("create index key: %w", err)
+		}
+		keys[key] = struct{}{}
+	}
+
+	if asset.ParentID != "" {
+		key, err := ctx.GetStub().CreateCompositeKey(componentIndex, []string{asset.ParentID, asset.ID})
+		if err != nil {
+			return nil, fmt.Errorf("create index key: %w", err)
+		}
+		keys[key] = struct{}{}
+	}
+
+	for _, tag := range asset.Tags {
+		key, err := ctx.GetStub().CreateCompositeKey(tagIndex, []string{tag, asset.ID})
+		if err != nil {
+			return nil, fmt.Errorf("create index key: %w", err)
+		}
+		keys[key] = struct{}{}
+	}
+
+	// Concealed values must not leak through the index, and negative legacy
+	// amounts would not sort correctly.
+	if !concealed(asset) && asset.Value.Amount >= 0 {
+		key, err := ctx.GetStub().CreateCompositeKey(valueIndex, []string{asset.Value.Currency, indexAmount(asset.Value.Amount), asset.ID})
+		if err != nil {
+			return nil, fmt.Errorf("create index key: %w", err)
+		}
+		keys[key] = struct{}{}
+	}
+
+	return keys, nil
+}
+
+// indexAmount formats a non-negative amount for value index keys.
+func indexAmount(amount int64) string {
+	return fmt.Sprintf("%0*d", valueAmountWidth, amount)
+}
+
+// indexTime normalizes an RFC3339 timestamp to indexTimeLayout.
+func indexTime(ts string) (string, error) {
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return "", err
+	}
+	return t.UTC().Format(indexTimeLayout), nil
+}
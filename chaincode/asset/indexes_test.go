@@ -0,0 +1,157 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func assetIDs(page *AssetPage) []string {
+	ids := []string{}
+	for _, a := range page.Assets {
+		ids = append(ids, a.ID)
+	}
+	return ids
+}
+
+func equalIDs(got, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestGetAssetsUpdatedSince(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("a", "Alice", "1 EUR")
+	since := e.ledger.clock.Add(time.Second).Format(time.RFC3339Nano)
+	e.createAsset("b", "Alice", "1 EUR")
+	e.createAsset("c", "Alice", "1 EUR")
+	mustOK(t, e.assets.UpdateAssetValue(e.tx(alice), "a", "2 EUR"))
+
+	page, err := e.assets.GetAssetsUpdatedSince(e.tx(bob), since, 2, "")
+	mustOK(t, err)
+	if !equalIDs(assetIDs(page), []string{"b", "c"}) || page.Bookmark == "" {
+		t.Fatalf("first page = %v, bookmark %q", assetIDs(page), page.Bookmark)
+	}
+	page, err = e.assets.GetAssetsUpdatedSince(e.tx(bob), since, 2, page.Bookmark)
+	mustOK(t, err)
+	if !equalIDs(assetIDs(page), []string{"a"}) || page.Bookmark != "" {
+		t.Fatalf("second page = %v, bookmark %q", assetIDs(page), page.Bookmark)
+	}
+
+	_, err = e.assets.GetAssetsUpdatedSince(e.tx(bob), "yesterday", 2, "")
+	wantErr(t, err, "invalid ts")
+	_, err = e.assets.GetAssetsUpdatedSince(e.tx(bob), since, 0, "")
+	wantErr(t, err, "pageSize must be > 0")
+}
+
+func TestSetAssetTagsAndGetAssetsByTag(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("a", "Alice", "1 EUR")
+	e.createAsset("b", "Alice", "1 EUR")
+	e.createAsset("c", alice.id(), "1 EUR")
+
+	mustOK(t, e.assets.SetAssetTags(e.tx(alice), "a", []string{" Insured", "warehouse-b", "insured"}))
+	mustOK(t, e.assets.SetAssetTags(e.tx(alice), "b", []string{"insured"}))
+	if tags := e.readAsset("a").Tags; !equalIDs(tags, []string{"insured", "warehouse-b"}) {
+		t.Fatalf("tags = %v", tags)
+	}
+
+	page, err := e.assets.GetAssetsByTag(e.tx(bob), "INSURED", 1, "")
+	mustOK(t, err)
+	if !equalIDs(assetIDs(page), []string{"a"}) || page.Bookmark == "" {
+		t.Fatalf("first page = %v, bookmark %q", assetIDs(page), page.Bookmark)
+	}
+	page, err = e.assets.GetAssetsByTag(e.tx(bob), "insured", 1, page.Bookmark)
+	mustOK(t, err)
+	if !equalIDs(assetIDs(page), []string{"b"}) || page.Bookmark != "" {
+		t.Fatalf("second page = %v, bookmark %q", assetIDs(page), page.Bookmark)
+	}
+
+	// Replacing the tags drops the old index entries.
+	mustOK(t, e.assets.SetAssetTags(e.tx(alice), "a", []string{}))
+	page, err = e.assets.GetAssetsByTag(e.tx(bob), "warehouse-b", 10, "")
+	mustOK(t, err)
+	if len(page.Assets) != 0 {
+		t.Fatalf("untagged asset still indexed: %v", assetIDs(page))
+	}
+
+	wantErr(t, e.assets.SetAssetTags(e.tx(alice), "a", []string{" "}), "is empty")
+	wantErr(t, e.assets.SetAssetTags(e.tx(bob), "c", []string{"x"}), "updateValue right")
+	_, err = e.assets.GetAssetsByTag(e.tx(bob), " ", 1, "")
+	wantErr(t, err, "tag is required")
+}
+
+func TestGetAssetsByValueRange(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("cheap", "Alice", "5 EUR")
+	e.createAsset("mid1", "Alice", "100 EUR")
+	e.createAsset("mid2", "Alice", "150.50 EUR")
+	e.createAsset("pricey", "Alice", "1000 EUR")
+	e.createAsset("dollars", "Alice", "100 USD")
+
+	page, err := e.assets.GetAssetsByValueRange(e.tx(bob), "100 EUR", "1000 EUR", 2, "")
+	mustOK(t, err)
+	if !equalIDs(assetIDs(page), []string{"mid1", "mid2"}) || page.Bookmark == "" || page.Count != 2 {
+		t.Fatalf("first page = %v, bookmark %q, count %d", assetIDs(page), page.Bookmark, page.Count)
+	}
+	page, err = e.assets.GetAssetsByValueRange(e.tx(bob), "100 EUR", "1000 EUR", 2, page.Bookmark)
+	mustOK(t, err)
+	if !equalIDs(assetIDs(page), []string{"pricey"}) || page.Bookmark != "" || page.Count != 1 {
+		t.Fatalf("second page = %v, bookmark %q, count %d", assetIDs(page), page.Bookmark, page.Count)
+	}
+
+	// The page stops at the upper bound even when more entries follow.
+	page, err = e.assets.GetAssetsByValueRange(e.tx(bob), "0 EUR", "100 EUR", 10, "")
+	mustOK(t, err)
+	if !equalIDs(assetIDs(page), []string{"cheap", "mid1"}) || page.Bookmark != "" || page.Count != 2 {
+		t.Fatalf("page = %v, bookmark %q, count %d", assetIDs(page), page.Bookmark, page.Count)
+	}
+
+	mustOK(t, e.assets.UpdateAssetValue(e.tx(alice), "cheap", "50 USD"))
+	page, err = e.assets.GetAssetsByValueRange(e.tx(bob), "0 USD", "100 USD", 10, "")
+	mustOK(t, err)
+	if !equalIDs(assetIDs(page), []string{"cheap", "dollars"}) {
+		t.Fatalf("USD page = %v", assetIDs(page))
+	}
+
+	_, err = e.assets.GetAssetsByValueRange(e.tx(bob), "1 EUR", "1 USD", 10, "")
+	wantErr(t, err, "same currency")
+	_, err = e.assets.GetAssetsByValueRange(e.tx(bob), "2 EUR", "1 EUR", 10, "")
+	wantErr(t, err, "min must not be greater than max")
+	_, err = e.assets.GetAssetsByValueRange(e.tx(bob), "x", "1 EUR", 10, "")
+	wantErr(t, err, "min")
+	_, err = e.assets.GetAssetsByValueRange(e.tx(bob), "1 EUR", "2 EUR", 0, "")
+	wantErr(t, err, "pageSize must be > 0")
+}
+
+func TestReindexAssets(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("a", "Alice", "1 EUR")
+	e.createAsset("b", "Alice", "2 EUR")
+
+	// Drop the index entries, as for assets stored before the indexes.
+	for key := range e.ledger.state {
+		if obj, _, err := e.tx(alice).stub.SplitCompositeKey(key); err == nil && (obj == valueIndex || obj == ownerIndex) {
+			delete(e.ledger.state, key)
+		}
+	}
+
+	_, err := e.assets.ReindexAssets(e.tx(alice))
+	wantErr(t, err, "only org admin identities may do this")
+	n, err := e.assets.ReindexAssets(e.tx(org1Admin))
+	mustOK(t, err)
+	if n != 2 {
+		t.Fatalf("reindexed %d assets, want 2", n)
+	}
+	page, err := e.assets.GetAssetsByValueRange(e.tx(bob), "0 EUR", "10 EUR", 10, "")
+	mustOK(t, err)
+	if !equalIDs(assetIDs(page), []string{"a", "b"}) {
+		t.Fatalf("page = %v", assetIDs(page))
+	}
+}
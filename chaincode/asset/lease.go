@@ -0,0 +1,319 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const leaseObjectType = "lease"
+
+// LeaseTerms control what the owner may still do while a lease is active.
+type LeaseTerms struct {
+	AllowTransfer bool   `json:"allowTransfer"`
+	AllowDelete   bool   `json:"allowDelete"`
+	Notes         string `json:"notes,omitempty" metadata:",optional"`
+}
+
+type Lease struct {
+	AssetID string     `json:"assetId"`
+	Lessor  string     `json:"lessor"`
+	Lessee  string     `json:"lessee"`
+	Start   string     `json:"start"`
+	End     string     `json:"end"`
+	Terms   LeaseTerms `json:"terms"`
+	TxID    string     `json:"txId"`
+}
+
+// LeaseAsset grants lessee the right to use an asset between start and end
+// (RFC3339). An asset has at most one current or upcoming lease.
+func (c *AssetContract) LeaseAsset(ctx contractapi.TransactionContextInterface, id string, lessee string, start string, end string, terms LeaseTerms) error {
+	termsJSON, err := json.Marshal(terms)
+	if err != nil {
+		return fmt.Errorf("marshal lease terms: %w", err)
+	}
+	guard, err := beginRequest(ctx, "LeaseAsset", id, lessee, start, end, string(termsJSON))
+	if err != nil {
+		return err
+	}
+	if _, ok := guard.replayed(); ok {
+		return nil
+	}
+
+	if err := requireOwnerMSP(ctx); err != nil {
+		return err
+	}
+
+	lessee, err = validOwner(ctx, "lessee", lessee)
+	if err != nil {
+		return err
+	}
+	startTime, err := time.Parse(time.RFC3339Nano, start)
+	if err != nil {
+		return fmt.Errorf("invalid start: %w", err)
+	}
+	endTime, err := time.Parse(time.RFC3339Nano, end)
+	if err != nil {
+		return fmt.Errorf("invalid end: %w", err)
+	}
+	if !endTime.After(startTime) {
+		return errors.New("end must be after start")
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	if !endTime.After(now) {
+		return errors.New("end must be in the future")
+	}
+
+	asset, err := c.ReadAsset(ctx, id)
+	if err != nil {
+		return err
+	}
+	a, err := authorize(ctx, asset, RightLease)
+	if err != nil {
+		return err
+	}
+	if err := checkNotShared(asset); err != nil {
+		return err
+	}
+	if err := checkNotFrozen(ctx, asset.ID); err != nil {
+		return err
+	}
+	if lessee == asset.Owner {
+		return errors.New("the owner cannot lease an asset to themselves")
+	}
+
+	existing, err := readLease(ctx, asset.ID)
+	if err != nil {
+		return err
+	}
+	if existing != nil && leaseEnd(existing).After(now) {
+		return fmt.Errorf("asset %s is already leased until %s", asset.ID, existing.End)
+	}
+
+	lease := Lease{
+		AssetID: asset.ID,
+		Lessor:  asset.Owner,
+		Lessee:  lessee,
+		Start:   startTime.UTC().Format(time.RFC3339Nano),
+		End:     endTime.UTC().Format(time.RFC3339Nano),
+		Terms:   terms,
+		TxID:    ctx.GetStub().GetTxID(),
+	}
+	if err := putLease(ctx, &lease); err != nil {
+		return err
+	}
+	if err := recordRelatedAudit(ctx, "LeaseAsset", asset.ID, "lease", existing, lease); err != nil {
+		return err
+	}
+	if err := emitOperation(ctx, "LeaseAsset", asset, asset.Owner, a); err != nil {
+		return err
+	}
+	return guard.complete(ctx, "")
+}
+
+// EndLease terminates the current or upcoming lease of an asset. Either the
+// lessor, an operator of the lessor holding the lease right, or the lessee
+// may end it. Leases by label lessors may be ended by anyone, as in authorize.
+func (c *AssetContract) EndLease(ctx contractapi.TransactionContextInterface, id string) error {
+	guard, err := beginRequest(ctx, "EndLease", id)
+	if err != nil {
+		return err
+	}
+	if _, ok := guard.replayed(); ok {
+		return nil
+	}
+
+	if err := requireOwnerMSP(ctx); err != nil {
+		return err
+	}
+
+	asset, err := c.ReadAsset(ctx, id)
+	if err != nil {
+		return err
+	}
+	lease, err := readLease(ctx, asset.ID)
+	if err != nil {
+		return err
+	}
+	if lease == nil {
+		return fmt.Errorf("asset %s is not leased", asset.ID)
+	}
+
+	invoker, err := submitterID(ctx)
+	if err != nil {
+		return err
+	}
+	operator := false
+	if invoker != lease.Lessor && invoker != lease.Lessee && isIdentity(lease.Lessor) {
+		operator, err = actsFor(ctx, invoker, lease.Lessor, asset.ID, RightLease)
+		if err != nil {
+			return err
+		}
+		if !operator {
+			return errors.New("only the lessor or the lessee may end a lease")
+		}
+	}
+
+	if err := clearLease(ctx, asset.ID); err != nil {
+		return err
+	}
+	if err := recordRelatedAudit(ctx, "EndLease", asset.ID, "lease", lease, nil); err != nil {
+		return err
+	}
+	if err := emitOperation(ctx, "EndLease", asset, lease.Lessor, &actor{id: invoker, operator: operator}); err != nil {
+		return err
+	}
+	return guard.complete(ctx, "")
+}
+
+// GetActiveLease returns the lease in effect at the transaction timestamp.
+func (c *AssetContract) GetActiveLease(ctx contractapi.TransactionContextInterface, id string) (*Lease, error) {
+	asset, err := c.ReadAsset(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	lease, err := activeLease(ctx, asset.ID)
+	if err != nil {
+		return nil, err
+	}
+	if lease == nil {
+		return nil, fmt.Errorf("asset %s has no active lease", asset.ID)
+	}
+	return lease, nil
+}
+
+// HasUsageRight reports whether identity may use the asset: its owner, or
+// the lessee while a lease is active.
+func (c *AssetContract) HasUsageRight(ctx contractapi.TransactionContextInterface, id string, identity string) (bool, error) {
+	identity, err := validOwner(ctx, "identity", identity)
+	if err != nil {
+		return false, err
+	}
+	asset, err := c.ReadAsset(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	lease, err := activeLease(ctx, asset.ID)
+	if err != nil {
+		return false, err
+	}
+	if lease != nil {
+		return identity == lease.Lessee, nil
+	}
+	return identity == asset.Owner, nil
+}
+
+// checkLeaseAllows fails when a current or upcoming lease forbids the owner
+// to transfer or delete the asset. An upcoming lease binds the owner as much
+// as a current one, since the lessee is promised the asset.
+func checkLeaseAllows(ctx contractapi.TransactionContextInterface, id string, transfer bool) error {
+	lease, err := unexpiredLease(ctx, id)
+	if err != nil || lease == nil {
+		return err
+	}
+	active, err := activeLease(ctx, id)
+	if err != nil {
+		return err
+	}
+	period := "until " + lease.End
+	if active == nil {
+		period = fmt.Sprintf("from %s until %s", lease.Start, lease.End)
+	}
+	if transfer && !lease.Terms.AllowTransfer {
+		return fmt.Errorf("asset %s is leased %s and the lease does not allow transfers", id, period)
+	}
+	if !transfer && !lease.Terms.AllowDelete {
+		return fmt.Errorf("asset %s is leased %s and the lease does not allow deletion", id, period)
+	}
+	return nil
+}
+
+// unexpiredLease returns the current or upcoming lease of an asset.
+func unexpiredLease(ctx contractapi.TransactionContextInterface, id string) (*Lease, error) {
+	lease, err := readLease(ctx, id)
+	if err != nil || lease == nil {
+		return nil, err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !leaseEnd(lease).After(now) {
+		return nil, nil
+	}
+	return lease, nil
+}
+
+func activeLease(ctx contractapi.TransactionContextInterface, id string) (*Lease, error) {
+	lease, err := readLease(ctx, id)
+	if err != nil || lease == nil {
+		return nil, err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	start, err := time.Parse(time.RFC3339Nano, lease.Start)
+	if err != nil {
+		return nil, fmt.Errorf("lease of %s: invalid start: %w", id, err)
+	}
+	if now.Before(start) || !leaseEnd(lease).After(now) {
+		return nil, nil
+	}
+	return lease, nil
+}
+
+// leaseEnd returns the end of a lease. Stored ends are always valid RFC3339.
+func leaseEnd(lease *Lease) time.Time {
+	end, _ := time.Parse(time.RFC3339Nano, lease.End)
+	return end
+}
+
+func readLease(ctx contractapi.TransactionContextInterface, id string) (*Lease, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(leaseObjectType, []string{id})
+	if err != nil {
+		return nil, fmt.Errorf("create lease key: %w", err)
+	}
+	b, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("get state: %w", err)
+	}
+	if b == nil {
+		return nil, nil
+	}
+	var lease Lease
+	if err := json.Unmarshal(b, &lease); err != nil {
+		return nil, fmt.Errorf("unmarshal lease: %w", err)
+	}
+	return &lease, nil
+}
+
+// clearLease removes the lease record of an asset, if any.
+func clearLease(ctx contractapi.TransactionContextInterface, id string) error {
+	key, err := ctx.GetStub().CreateCompositeKey(leaseObjectType, []string{id})
+	if err != nil {
+		return fmt.Errorf("create lease key: %w", err)
+	}
+	if err := ctx.GetStub().DelState(key); err != nil {
+		return fmt.Errorf("delete lease: %w", err)
+	}
+	return nil
+}
+
+func putLease(ctx contractapi.TransactionContextInterface, lease *Lease) error {
+	key, err := ctx.GetStub().CreateCompositeKey(leaseObjectType, []string{lease.AssetID})
+	if err != nil {
+		return fmt.Errorf("create lease key: %w", err)
+	}
+	b, err := json.Marshal(lease)
+	if err != nil {
+		return fmt.Errorf("marshal lease: %w", err)
+	}
+	return ctx.GetStub().PutState(key, b)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLeaseAsset(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("van", alice.id(), "20000 EUR")
+	e.verifyOwners(carol.id())
+
+	now := e.ledger.clock
+	start := now.Add(time.Hour).Format(time.RFC3339)
+	end := now.Add(48 * time.Hour).Format(time.RFC3339)
+
+	wantErr(t, e.assets.LeaseAsset(e.tx(bob), "van", bob.id(), start, end, LeaseTerms{}), "lease right")
+	wantErr(t, e.assets.LeaseAsset(e.tx(alice), "van", " ", start, end, LeaseTerms{}), "lessee is required")
+	wantErr(t, e.assets.LeaseAsset(e.tx(alice), "van", bob.id(), end, start, LeaseTerms{}), "end must be after start")
+	wantErr(t, e.assets.LeaseAsset(e.tx(alice), "van", bob.id(), "soon", end, LeaseTerms{}), "invalid start")
+	wantErr(t, e.assets.LeaseAsset(e.tx(alice), "van", alice.id(), start, end, LeaseTerms{}), "cannot lease an asset to themselves")
+	mustOK(t, e.assets.LeaseAsset(e.tx(alice), "van", bob.id(), start, end, LeaseTerms{AllowTransfer: true}))
+	wantErr(t, e.assets.LeaseAsset(e.tx(alice), "van", carol.id(), start, end, LeaseTerms{}), "already leased")
+
+	// Upcoming leases are not active yet.
+	_, err := e.assets.GetActiveLease(e.tx(bob), "van")
+	wantErr(t, err, "has no active lease")
+	usage, err := e.assets.HasUsageRight(e.tx(bob), "van", alice.id())
+	mustOK(t, err)
+	if !usage {
+		t.Fatal("owner has no usage right before the lease starts")
+	}
+
+	e.ledger.advance(2 * time.Hour)
+	lease, err := e.assets.GetActiveLease(e.tx(bob), "van")
+	mustOK(t, err)
+	if lease.Lessee != bob.id() || lease.Lessor != alice.id() {
+		t.Fatalf("unexpected lease %+v", lease)
+	}
+	for identity, want := range map[string]bool{alice.id(): false, bob.id(): true} {
+		got, err := e.assets.HasUsageRight(e.tx(bob), "van", identity)
+		mustOK(t, err)
+		if got != want {
+			t.Errorf("HasUsageRight(%s) = %v, want %v", identity, got, want)
+		}
+	}
+
+	wantErr(t, e.assets.DeleteAsset(e.tx(alice), "van"), "does not allow deletion")
+	mustOK(t, e.assets.UpdateAssetOwner(e.tx(alice), "van", carol.id()))
+
+	wantErr(t, e.assets.EndLease(e.tx(carol), "van"), "only the lessor or the lessee")
+	mustOK(t, e.assets.EndLease(e.tx(bob), "van"))
+	wantErr(t, e.assets.EndLease(e.tx(bob), "van"), "asset van is not leased")
+}
+
+func TestLeaseBlocksTransfer(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("van", alice.id(), "20000 EUR")
+	e.verifyOwners(carol.id())
+
+	now := e.ledger.clock
+	mustOK(t, e.assets.LeaseAsset(e.tx(alice), "van", bob.id(), now.Format(time.RFC3339), now.Add(time.Hour).Format(time.RFC3339), LeaseTerms{}))
+	wantErr(t, e.assets.UpdateAssetOwner(e.tx(alice), "van", carol.id()), "does not allow transfers")
+
+	e.ledger.advance(2 * time.Hour)
+	mustOK(t, e.assets.UpdateAssetOwner(e.tx(alice), "van", carol.id()))
+}
+
+func TestUpcomingLeaseBlocksTransfer(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("van", alice.id(), "20000 EUR")
+	e.verifyOwners(carol.id())
+
+	start := e.ledger.clock.Add(24 * time.Hour)
+	mustOK(t, e.assets.LeaseAsset(e.tx(alice), "van", bob.id(), start.Format(time.RFC3339), start.Add(time.Hour).Format(time.RFC3339), LeaseTerms{}))
+	period := "from " + start.Format(time.RFC3339) + " until " + start.Add(time.Hour).Format(time.RFC3339)
+	wantErr(t, e.assets.UpdateAssetOwner(e.tx(alice), "van", carol.id()), "asset van is leased "+period+" and the lease does not allow transfers")
+	wantErr(t, e.assets.DeleteAsset(e.tx(alice), "van"), "asset van is leased "+period+" and the lease does not allow deletion")
+
+	e.ledger.advance(25 * time.Hour)
+	mustOK(t, e.assets.UpdateAssetOwner(e.tx(alice), "van", carol.id()))
+}
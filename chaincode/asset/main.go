@@ -2,12 +2,39 @@ package main
 
 import (
 	"log"
+	"os"
+	"strings"
 
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
 )
 
 func main() {
-	chaincode, err := contractapi.NewChaincode(&AssetContract{})
+	complianceMSP := os.Getenv("ASSET_COMPLIANCE_MSP")
+	assetContract := &AssetContract{
+		// These must be identical on every endorsing peer, otherwise
+		// endorsements will not match.
+		AutoIDPrefix:  os.Getenv("ASSET_ID_PREFIX"),
+		RegulatorMSP:  os.Getenv("ASSET_REGULATOR_MSP"),
+		ArbitratorMSP: os.Getenv("ASSET_ARBITRATOR_MSP"),
+		ComplianceMSP: complianceMSP,
+		GovernanceMSP: os.Getenv("ASSET_GOVERNANCE_MSP"),
+	}
+	// A comma separated list, e.g. "Org2MSP,Org4MSP".
+	for _, msp := range strings.Split(os.Getenv("ASSET_APPRAISER_MSPS"), ",") {
+		if msp = strings.TrimSpace(msp); msp != "" {
+			assetContract.AppraiserMSPs = append(assetContract.AppraiserMSPs, msp)
+		}
+	}
+	assetContract.BeforeTransaction = checkFunctionEnabled
+
+	nftContract := NewNFTContract(assetContract)
+	nftContract.BaseURI = os.Getenv("NFT_BASE_URI")
+	nftContract.BeforeTransaction = checkFunctionEnabled
+
+	registryContract := &OwnerRegistryContract{ComplianceMSP: complianceMSP}
+	registryContract.BeforeTransaction = checkFunctionEnabled
+
+	chaincode, err := contractapi.NewChaincode(assetContract, nftContract, registryContract)
 	if err != nil {
 		log.Panicf("Error creating chaincode: %v", err)
 	}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// TestChaincodeMetadata checks that the contracts satisfy the contract API's
+// rules for transaction functions and schema types.
+func TestChaincodeMetadata(t *testing.T) {
+	assets := &AssetContract{}
+	if _, err := contractapi.NewChaincode(assets, NewNFTContract(assets), &OwnerRegistryContract{}); err != nil {
+		t.Fatalf("create chaincode: %v", err)
+	}
+}
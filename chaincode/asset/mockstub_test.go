@@ -0,0 +1,666 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// mockLedger is an in-memory world state shared by the transactions of a
+// test. It keeps the history of every key and the private data of every
+// collection. As on a peer, the writes of a transaction are buffered and
+// reads only see committed state. Buffered writes are committed when the
+// next transaction starts, or by commit.
+type mockLedger struct {
+	state   map[string][]byte
+	history map[string][]*queryresult.KeyModification
+	private map[string]map[string][]byte
+	// purged lists the "collection/key" pairs passed to PurgePrivateData.
+	purged []string
+	// members are the member MSPs of the explicit collections, see
+	// loadCollections. Implicit collections need no configuration.
+	members map[string][]string
+
+	clock   time.Time
+	txCount int
+	// txs are the transactions started so far, in order.
+	txs []*mockStub
+}
+
+func newMockLedger() *mockLedger {
+	return &mockLedger{
+		state:   make(map[string][]byte),
+		history: make(map[string][]*queryresult.KeyModification),
+		private: make(map[string]map[string][]byte),
+		members: make(map[string][]string),
+		clock:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+var memberPattern = regexp.MustCompile(`'([^'.]+)\.member'`)
+
+// loadCollections reads the explicit collections and their members from a
+// collection configuration file as passed to peer lifecycle.
+func (l *mockLedger) loadCollections(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var collections []struct {
+		Name   string `json:"name"`
+		Policy string `json:"policy"`
+	}
+	if err := json.Unmarshal(b, &collections); err != nil {
+		return fmt.Errorf("unmarshal %s: %w", path, err)
+	}
+	for _, c := range collections {
+		for _, m := range memberPattern.FindAllStringSubmatch(c.Policy, -1) {
+			l.members[c.Name] = append(l.members[c.Name], m[1])
+		}
+	}
+	return nil
+}
+
+// advance moves the ledger clock forward.
+func (l *mockLedger) advance(d time.Duration) {
+	l.clock = l.clock.Add(d)
+}
+
+// newTx starts a transaction invoked by identity. Every transaction gets a
+// fresh 64 hex character ID and a timestamp one second after the previous
+// one.
+func (l *mockLedger) newTx(identity *mockIdentity, function string, args ...string) *mockContext {
+	l.commit()
+	l.txCount++
+	l.clock = l.clock.Add(time.Second)
+	sum := sha256.Sum256([]byte(strconv.Itoa(l.txCount)))
+	stub := &mockStub{
+		ledger:    l,
+		mspID:     identity.mspID,
+		txID:      hex.EncodeToString(sum[:]),
+		timestamp: l.clock,
+		function:  function,
+		args:      args,
+		transient: make(map[string][]byte),
+		writes:    make(map[string][]byte),
+		private:   make(map[string]map[string][]byte),
+	}
+	l.txs = append(l.txs, stub)
+	return &mockContext{stub: stub, identity: identity}
+}
+
+// commit applies the buffered writes of all transactions, oldest first.
+// Transactions stay open, so a context used again later commits its new
+// writes with the next call.
+func (l *mockLedger) commit() {
+	for _, s := range l.txs {
+		for _, key := range sortedKeys(s.writes) {
+			value := s.writes[key]
+			if value == nil {
+				delete(l.state, key)
+			} else {
+				l.state[key] = value
+			}
+			s.recordHistory(key, value, value == nil)
+		}
+		s.writes = make(map[string][]byte)
+
+		for collection, writes := range s.private {
+			if l.private[collection] == nil {
+				l.private[collection] = make(map[string][]byte)
+			}
+			for key, value := range writes {
+				if value == nil {
+					delete(l.private[collection], key)
+				} else {
+					l.private[collection][key] = value
+				}
+			}
+		}
+		s.private = make(map[string]map[string][]byte)
+		l.purged = append(l.purged, s.purges...)
+		s.purges = nil
+	}
+}
+
+func sortedKeys(m map[string][]byte) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// mockContext implements contractapi.TransactionContextInterface.
+type mockContext struct {
+	stub     *mockStub
+	identity *mockIdentity
+}
+
+func (c *mockContext) GetStub() shim.ChaincodeStubInterface {
+	return c.stub
+}
+
+func (c *mockContext) GetClientIdentity() cid.ClientIdentity {
+	return c.identity
+}
+
+// withTransient sets a transient data entry of the transaction.
+func (c *mockContext) withTransient(key string, value string) *mockContext {
+	c.stub.transient[key] = []byte(value)
+	return c
+}
+
+// mockStub implements shim.ChaincodeStubInterface for a single transaction
+// on a mockLedger.
+type mockStub struct {
+	ledger    *mockLedger
+	mspID     string
+	txID      string
+	timestamp time.Time
+	function  string
+	args      []string
+	transient map[string][]byte
+	// event is the last event set, as only one event per transaction
+	// reaches the block.
+	event *peer.ChaincodeEvent
+
+	// writes and private buffer the writes of the transaction until the
+	// ledger commits them. A nil value is a delete.
+	writes  map[string][]byte
+	private map[string]map[string][]byte
+	purges  []string
+}
+
+func (s *mockStub) GetArgs() [][]byte {
+	args := [][]byte{[]byte(s.function)}
+	for _, a := range s.args {
+		args = append(args, []byte(a))
+	}
+	return args
+}
+
+func (s *mockStub) GetStringArgs() []string {
+	return append([]string{s.function}, s.args...)
+}
+
+func (s *mockStub) GetFunctionAndParameters() (string, []string) {
+	return s.function, s.args
+}
+
+func (s *mockStub) GetArgsSlice() ([]byte, error) {
+	var out []byte
+	for _, a := range s.GetArgs() {
+		out = append(out, a...)
+	}
+	return out, nil
+}
+
+func (s *mockStub) GetTxID() string {
+	return s.txID
+}
+
+func (s *mockStub) GetChannelID() string {
+	return "mychannel"
+}
+
+func (s *mockStub) InvokeChaincode(chaincodeName string, args [][]byte, channel string) peer.Response {
+	return shim.Error("mockStub does not support chaincode to chaincode calls")
+}
+
+func (s *mockStub) GetState(key string) ([]byte, error) {
+	return s.ledger.state[key], nil
+}
+
+func (s *mockStub) PutState(key string, value []byte) error {
+	if key == "" {
+		return errors.New("key must not be an empty string")
+	}
+	if len(value) == 0 {
+		// The peer treats an empty value as a delete.
+		return s.DelState(key)
+	}
+	s.writes[key] = append([]byte(nil), value...)
+	return nil
+}
+
+func (s *mockStub) DelState(key string) error {
+	if key == "" {
+		return errors.New("key must not be an empty string")
+	}
+	s.writes[key] = nil
+	return nil
+}
+
+// recordHistory keeps one modification per key and transaction, the last
+// write, as the peer does. The ledger calls it on commit.
+func (s *mockStub) recordHistory(key string, value []byte, isDelete bool) {
+	mod := &queryresult.KeyModification{
+		TxId:      s.txID,
+		Value:     append([]byte(nil), value...),
+		Timestamp: timestamppb.New(s.timestamp),
+		IsDelete:  isDelete,
+	}
+	mods := s.ledger.history[key]
+	if n := len(mods); n > 0 && mods[n-1].TxId == s.txID {
+		mods[n-1] = mod
+		return
+	}
+	s.ledger.history[key] = append(mods, mod)
+}
+
+func (s *mockStub) SetStateValidationParameter(key string, ep []byte) error {
+	return errors.New("mockStub does not support key level endorsement")
+}
+
+func (s *mockStub) GetStateValidationParameter(key string) ([]byte, error) {
+	return nil, errors.New("mockStub does not support key level endorsement")
+}
+
+func (s *mockStub) GetStateByRange(startKey, endKey string) (shim.StateQueryIteratorInterface, error) {
+	if startKey == "" {
+		startKey = "\x01"
+	}
+	if err := validateSimpleKeys(startKey, endKey); err != nil {
+		return nil, err
+	}
+	return newMockIterator(rangeKVs(s.ledger.state, startKey, endKey)), nil
+}
+
+func (s *mockStub) GetStateByRangeWithPagination(startKey, endKey string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	if startKey == "" {
+		startKey = "\x01"
+	}
+	if err := validateSimpleKeys(startKey, endKey); err != nil {
+		return nil, nil, err
+	}
+	return paginate(rangeKVs(s.ledger.state, startKey, endKey), pageSize, bookmark)
+}
+
+func (s *mockStub) GetStateByPartialCompositeKey(objectType string, keys []string) (shim.StateQueryIteratorInterface, error) {
+	prefix, err := s.CreateCompositeKey(objectType, keys)
+	if err != nil {
+		return nil, err
+	}
+	return newMockIterator(rangeKVs(s.ledger.state, prefix, prefix+string(utf8.MaxRune))), nil
+}
+
+func (s *mockStub) GetStateByPartialCompositeKeyWithPagination(objectType string, keys []string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	prefix, err := s.CreateCompositeKey(objectType, keys)
+	if err != nil {
+		return nil, nil, err
+	}
+	return paginate(rangeKVs(s.ledger.state, prefix, prefix+string(utf8.MaxRune)), pageSize, bookmark)
+}
+
+func (s *mockStub) CreateCompositeKey(objectType string, attributes []string) (string, error) {
+	return shim.CreateCompositeKey(objectType, attributes)
+}
+
+func (s *mockStub) SplitCompositeKey(compositeKey string) (string, []string, error) {
+	if !strings.HasPrefix(compositeKey, "\x00") {
+		return "", nil, fmt.Errorf("%q is not a composite key", compositeKey)
+	}
+	parts := strings.Split(compositeKey[1:], "\x00")
+	// The key ends with a separator, leaving an empty last part.
+	parts = parts[:len(parts)-1]
+	if len(parts) == 0 {
+		return "", nil, fmt.Errorf("%q is not a composite key", compositeKey)
+	}
+	return parts[0], parts[1:], nil
+}
+
+func (s *mockStub) GetQueryResult(query string) (shim.StateQueryIteratorInterface, error) {
+	return nil, errors.New("mockStub does not support rich queries")
+}
+
+func (s *mockStub) GetQueryResultWithPagination(query string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	return nil, nil, errors.New("mockStub does not support rich queries")
+}
+
+// GetHistoryForKey returns the modifications of key newest first, like
+// Fabric 2.x peers.
+func (s *mockStub) GetHistoryForKey(key string) (shim.HistoryQueryIteratorInterface, error) {
+	mods := s.ledger.history[key]
+	out := make([]*queryresult.KeyModification, 0, len(mods))
+	for i := len(mods) - 1; i >= 0; i-- {
+		out = append(out, mods[i])
+	}
+	return &mockHistoryIterator{mods: out}, nil
+}
+
+func (s *mockStub) GetPrivateData(collection, key string) ([]byte, error) {
+	if collection == "" {
+		return nil, errors.New("collection must not be an empty string")
+	}
+	return s.ledger.private[collection][key], nil
+}
+
+func (s *mockStub) GetPrivateDataHash(collection, key string) ([]byte, error) {
+	if collection == "" {
+		return nil, errors.New("collection must not be an empty string")
+	}
+	value, ok := s.ledger.private[collection][key]
+	if !ok {
+		return nil, nil
+	}
+	sum := sha256.Sum256(value)
+	return sum[:], nil
+}
+
+func (s *mockStub) PutPrivateData(collection string, key string, value []byte) error {
+	if err := s.checkWrite(collection); err != nil {
+		return err
+	}
+	if key == "" {
+		return errors.New("key must not be an empty string")
+	}
+	s.privateWrites(collection)[key] = append([]byte(nil), value...)
+	return nil
+}
+
+func (s *mockStub) DelPrivateData(collection, key string) error {
+	if err := s.checkWrite(collection); err != nil {
+		return err
+	}
+	s.privateWrites(collection)[key] = nil
+	return nil
+}
+
+func (s *mockStub) PurgePrivateData(collection, key string) error {
+	if err := s.checkWrite(collection); err != nil {
+		return err
+	}
+	s.privateWrites(collection)[key] = nil
+	s.purges = append(s.purges, collection+"/"+key)
+	return nil
+}
+
+// checkWrite enforces memberOnlyWrite: only members of a collection may
+// write to it, as implicit collections and collections_config.json require.
+func (s *mockStub) checkWrite(collection string) error {
+	if collection == "" {
+		return errors.New("collection must not be an empty string")
+	}
+	members, ok := s.ledger.members[collection]
+	if mspID, implicit := strings.CutPrefix(collection, "_implicit_org_"); implicit {
+		members, ok = []string{mspID}, true
+	}
+	if !ok {
+		return fmt.Errorf("collection %s is not defined", collection)
+	}
+	for _, m := range members {
+		if m == s.mspID {
+			return nil
+		}
+	}
+	return fmt.Errorf("tx creator does not have write access permission on privatedata in collection %s", collection)
+}
+
+func (s *mockStub) privateWrites(collection string) map[string][]byte {
+	if s.private[collection] == nil {
+		s.private[collection] = make(map[string][]byte)
+	}
+	return s.private[collection]
+}
+
+func (s *mockStub) SetPrivateDataValidationParameter(collection, key string, ep []byte) error {
+	return errors.New("mockStub does not support key level endorsement")
+}
+
+func (s *mockStub) GetPrivateDataValidationParameter(collection, key string) ([]byte, error) {
+	return nil, errors.New("mockStub does not support key level endorsement")
+}
+
+func (s *mockStub) GetPrivateDataByRange(collection, startKey, endKey string) (shim.StateQueryIteratorInterface, error) {
+	if collection == "" {
+		return nil, errors.New("collection must not be an empty string")
+	}
+	if startKey == "" {
+		startKey = "\x01"
+	}
+	if err := validateSimpleKeys(startKey, endKey); err != nil {
+		return nil, err
+	}
+	return newMockIterator(rangeKVs(s.ledger.private[collection], startKey, endKey)), nil
+}
+
+func (s *mockStub) GetPrivateDataByPartialCompositeKey(collection, objectType string, keys []string) (shim.StateQueryIteratorInterface, error) {
+	if collection == "" {
+		return nil, errors.New("collection must not be an empty string")
+	}
+	prefix, err := s.CreateCompositeKey(objectType, keys)
+	if err != nil {
+		return nil, err
+	}
+	return newMockIterator(rangeKVs(s.ledger.private[collection], prefix, prefix+string(utf8.MaxRune))), nil
+}
+
+func (s *mockStub) GetPrivateDataQueryResult(collection, query string) (shim.StateQueryIteratorInterface, error) {
+	return nil, errors.New("mockStub does not support rich queries")
+}
+
+func (s *mockStub) GetCreator() ([]byte, error) {
+	return nil, errors.New("mockStub does not provide a serialized creator; use the client identity")
+}
+
+func (s *mockStub) GetTransient() (map[string][]byte, error) {
+	return s.transient, nil
+}
+
+func (s *mockStub) GetBinding() ([]byte, error) {
+	return nil, errors.New("mockStub does not provide a binding")
+}
+
+func (s *mockStub) GetDecorations() map[string][]byte {
+	return nil
+}
+
+func (s *mockStub) GetSignedProposal() (*peer.SignedProposal, error) {
+	return nil, errors.New("mockStub does not provide a signed proposal")
+}
+
+func (s *mockStub) GetTxTimestamp() (*timestamppb.Timestamp, error) {
+	return timestamppb.New(s.timestamp), nil
+}
+
+func (s *mockStub) SetEvent(name string, payload []byte) error {
+	if name == "" {
+		return errors.New("event name can not be empty string")
+	}
+	s.event = &peer.ChaincodeEvent{EventName: name, Payload: payload, TxId: s.txID}
+	return nil
+}
+
+func validateSimpleKeys(keys ...string) error {
+	for _, key := range keys {
+		if strings.HasPrefix(key, "\x00") {
+			return fmt.Errorf("first character of the key [%s] contains a null character which is not allowed", key)
+		}
+	}
+	return nil
+}
+
+// rangeKVs returns the entries of m with startKey <= key < endKey in key
+// order. An empty endKey is unbounded.
+func rangeKVs(m map[string][]byte, startKey, endKey string) []*queryresult.KV {
+	var kvs []*queryresult.KV
+	for k, v := range m {
+		if k < startKey || (endKey != "" && k >= endKey) {
+			continue
+		}
+		kvs = append(kvs, &queryresult.KV{Namespace: "asset", Key: k, Value: v})
+	}
+	sort.Slice(kvs, func(i, j int) bool { return kvs[i].Key < kvs[j].Key })
+	return kvs
+}
+
+// paginate returns a page of kvs the way the peer does: a non-empty bookmark
+// replaces the start key, and the returned bookmark is the key of the first
+// entry of the next page, or empty after the last page.
+func paginate(kvs []*queryresult.KV, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	if pageSize <= 0 {
+		return nil, nil, errors.New("pageSize must be > 0")
+	}
+	start := 0
+	if bookmark != "" {
+		start = sort.Search(len(kvs), func(i int) bool { return kvs[i].Key >= bookmark })
+	}
+	end := start + int(pageSize)
+	next := ""
+	if end < len(kvs) {
+		next = kvs[end].Key
+	} else {
+		end = len(kvs)
+	}
+	page := kvs[start:end]
+	return newMockIterator(page), &peer.QueryResponseMetadata{FetchedRecordsCount: int32(len(page)), Bookmark: next}, nil
+}
+
+type mockIterator struct {
+	kvs    []*queryresult.KV
+	closed bool
+}
+
+func newMockIterator(kvs []*queryresult.KV) *mockIterator {
+	return &mockIterator{kvs: kvs}
+}
+
+func (it *mockIterator) HasNext() bool {
+	return !it.closed && len(it.kvs) > 0
+}
+
+func (it *mockIterator) Next() (*queryresult.KV, error) {
+	if !it.HasNext() {
+		return nil, errors.New("no more results")
+	}
+	kv := it.kvs[0]
+	it.kvs = it.kvs[1:]
+	return kv, nil
+}
+
+func (it *mockIterator) Close() error {
+	it.closed = true
+	return nil
+}
+
+type mockHistoryIterator struct {
+	mods   []*queryresult.KeyModification
+	closed bool
+}
+
+func (it *mockHistoryIterator) HasNext() bool {
+	return !it.closed && len(it.mods) > 0
+}
+
+func (it *mockHistoryIterator) Next() (*queryresult.KeyModification, error) {
+	if !it.HasNext() {
+		return nil, errors.New("no more results")
+	}
+	mod := it.mods[0]
+	it.mods = it.mods[1:]
+	return mod, nil
+}
+
+func (it *mockHistoryIterator) Close() error {
+	it.closed = true
+	return nil
+}
+
+// mockIdentity implements cid.ClientIdentity for an X.509 identity with the
+// given common name and node OUs.
+type mockIdentity struct {
+	mspID string
+	cn    string
+	ous   []string
+	attrs map[string]string
+}
+
+func newMockIdentity(mspID string, cn string, ous ...string) *mockIdentity {
+	return &mockIdentity{mspID: mspID, cn: cn, ous: ous, attrs: map[string]string{}}
+}
+
+// id returns the identity in the form returned by submitterID.
+func (m *mockIdentity) id() string {
+	subject := "CN=" + m.cn
+	for _, ou := range m.ous {
+		subject += ",OU=" + ou
+	}
+	return "x509::" + subject + "::CN=ca." + strings.ToLower(strings.TrimSuffix(m.mspID, "MSP")) + ".example.com"
+}
+
+func (m *mockIdentity) GetID() (string, error) {
+	return base64.StdEncoding.EncodeToString([]byte(m.id())), nil
+}
+
+func (m *mockIdentity) GetMSPID() (string, error) {
+	return m.mspID, nil
+}
+
+func (m *mockIdentity) GetAttributeValue(attrName string) (string, bool, error) {
+	v, ok := m.attrs[attrName]
+	return v, ok, nil
+}
+
+func (m *mockIdentity) AssertAttributeValue(attrName, attrValue string) error {
+	if v, ok := m.attrs[attrName]; !ok || v != attrValue {
+		return fmt.Errorf("attribute '%s' equals '%s', not '%s'", attrName, v, attrValue)
+	}
+	return nil
+}
+
+func (m *mockIdentity) GetX509Certificate() (*x509.Certificate, error) {
+	return &x509.Certificate{
+		Subject: pkix.Name{CommonName: m.cn, OrganizationalUnit: m.ous},
+	}, nil
+}
+
+var (
+	_ shim.ChaincodeStubInterface             = (*mockStub)(nil)
+	_ contractapi.TransactionContextInterface = (*mockContext)(nil)
+	_ cid.ClientIdentity                      = (*mockIdentity)(nil)
+)
+
+func TestMockReadsCommittedState(t *testing.T) {
+	l := newMockLedger()
+	ctx := l.newTx(alice, "")
+	mustOK(t, ctx.stub.PutState("k", []byte("v")))
+	if b, _ := ctx.stub.GetState("k"); b != nil {
+		t.Fatalf("transaction read its own write %q", b)
+	}
+	iter, _ := ctx.stub.GetStateByRange("", "")
+	if iter.HasNext() {
+		t.Fatal("range query saw an uncommitted write")
+	}
+
+	next := l.newTx(alice, "")
+	if b, _ := next.stub.GetState("k"); string(b) != "v" {
+		t.Fatalf("committed value = %q", b)
+	}
+	mustOK(t, next.stub.DelState("k"))
+	l.commit()
+	if _, ok := l.state["k"]; ok || len(l.history["k"]) != 2 {
+		t.Fatalf("state %v, history %v", l.state, l.history["k"])
+	}
+}
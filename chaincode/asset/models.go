@@ -3,8 +3,29 @@ package main
 type Asset struct {
 	ID        string `json:"id"`
 	Owner     string `json:"owner"`
-	Value     int64  `json:"value"`
+	Value     Money  `json:"value"`
 	CreatedAt string `json:"createdAt"`
 	UpdatedAt string `json:"updatedAt"`
 	Version   int64  `json:"version"`
+	ParentID  string `json:"parentId,omitempty" metadata:",optional"`
+	// Tags are normalized to lower case, unique and sorted.
+	Tags []string `json:"tags,omitempty" metadata:",optional"`
+	// Shares is set while the asset is held by more than one party. Owner is
+	// then the holder of the largest share. Without shares, Owner holds the
+	// whole asset.
+	Shares []*OwnershipShare `json:"shares,omitempty" metadata:",optional"`
+	// ValueCommitment is the hex sha256 of the value and a salt for assets
+	// whose value is concealed. Value is then zero until RevealValue sets it
+	// together with ValueSalt. The opening is kept in ValueCollection.
+	ValueCommitment string `json:"valueCommitment,omitempty" metadata:",optional"`
+	ValueSalt       string `json:"valueSalt,omitempty" metadata:",optional"`
+	ValueCollection string `json:"valueCollection,omitempty" metadata:",optional"`
+	// ValuationID is the accepted valuation the value was taken from. It is
+	// cleared when the value is set directly.
+	ValuationID string `json:"valuationId,omitempty" metadata:",optional"`
+}
+
+type OwnershipShare struct {
+	Holder      string `json:"holder"`
+	BasisPoints int    `json:"basisPoints"`
 }
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// legacyCurrency is the ISO-4217 "no currency" code assigned to values stored
+// as bare integers before assets carried a currency.
+const legacyCurrency = "XXX"
+
+// currencyDecimals lists the supported ISO-4217 currencies and their number
+// of minor unit digits.
+var currencyDecimals = map[string]int{
+	"AUD": 2,
+	"BHD": 3,
+	"CAD": 2,
+	"CHF": 2,
+	"CNY": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"HKD": 2,
+	"IDR": 2,
+	"INR": 2,
+	"JOD": 3,
+	"JPY": 0,
+	"KRW": 0,
+	"KWD": 3,
+	"MYR": 2,
+	"NZD": 2,
+	"OMR": 3,
+	"PHP": 2,
+	"SGD": 2,
+	"THB": 2,
+	"USD": 2,
+	"VND": 0,
+}
+
+// Money is an amount in minor units of an ISO-4217 currency, e.g.
+// {12550, "EUR", 2} for 125.50 EUR.
+type Money struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+	Decimals int    `json:"decimals"`
+}
+
+// ParseMoney parses values like "125.50 EUR". The fraction may be shorter
+// than the currency's minor units but not longer.
+func ParseMoney(s string) (Money, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return Money{}, fmt.Errorf("value %q must be an amount followed by a currency, e.g. \"125.50 EUR\"", s)
+	}
+	number, currency := fields[0], strings.ToUpper(fields[1])
+
+	decimals, ok := currencyDecimals[currency]
+	if !ok {
+		return Money{}, fmt.Errorf("unsupported currency %q", fields[1])
+	}
+
+	negative := strings.HasPrefix(number, "-")
+	number = strings.TrimPrefix(number, "-")
+	whole, frac, point := strings.Cut(number, ".")
+	if whole == "" || (point && frac == "") || strings.Trim(whole+frac, "0123456789") != "" {
+		return Money{}, fmt.Errorf("invalid amount %q", fields[0])
+	}
+	if len(frac) > decimals {
+		return Money{}, fmt.Errorf("%s allows at most %d decimal places", currency, decimals)
+	}
+	frac += strings.Repeat("0", decimals-len(frac))
+
+	amount, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("invalid amount %q: %w", fields[0], err)
+	}
+	if negative {
+		amount = -amount
+	}
+	return Money{Amount: amount, Currency: currency, Decimals: decimals}, nil
+}
+
+func (m Money) String() string {
+	amount := strconv.FormatInt(m.Amount, 10)
+	sign := ""
+	if m.Amount < 0 {
+		sign, amount = "-", amount[1:]
+	}
+	if m.Decimals > 0 {
+		if len(amount) <= m.Decimals {
+			amount = strings.Repeat("0", m.Decimals-len(amount)+1) + amount
+		}
+		amount = amount[:len(amount)-m.Decimals] + "." + amount[len(amount)-m.Decimals:]
+	}
+	return sign + amount + " " + m.Currency
+}
+
+// UnmarshalJSON also accepts the legacy bare integer form of Asset.Value.
+func (m *Money) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] != '{' && string(b) != "null" {
+		var amount int64
+		if err := json.Unmarshal(b, &amount); err != nil {
+			return fmt.Errorf("legacy value: %w", err)
+		}
+		*m = Money{Amount: amount, Currency: legacyCurrency}
+		return nil
+	}
+
+	type plain Money
+	return json.Unmarshal(b, (*plain)(m))
+}
+
+// parseValue parses an asset value argument and applies the contract's
+// value rules to it.
+func parseValue(name string, raw string) (Money, error) {
+	m, err := ParseMoney(raw)
+	if err != nil {
+		return Money{}, fmt.Errorf("%s: %w", name, err)
+	}
+	if m.Amount < 0 {
+		return Money{}, fmt.Errorf("%s must be >= 0", name)
+	}
+	return m, nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Money
+		err  string
+	}{
+		{in: "125.50 EUR", want: Money{Amount: 12550, Currency: "EUR", Decimals: 2}},
+		{in: "125.5 eur", want: Money{Amount: 12550, Currency: "EUR", Decimals: 2}},
+		{in: "1000 JPY", want: Money{Amount: 1000, Currency: "JPY"}},
+		{in: "0.125 BHD", want: Money{Amount: 125, Currency: "BHD", Decimals: 3}},
+		{in: "-3 USD", want: Money{Amount: -300, Currency: "USD", Decimals: 2}},
+		{in: "125.50", err: "must be an amount followed by a currency"},
+		{in: "10 XYZ", err: `unsupported currency "XYZ"`},
+		{in: "1.2.3 EUR", err: `invalid amount "1.2.3"`},
+		{in: ".5 EUR", err: `invalid amount ".5"`},
+		{in: "5. EUR", err: `invalid amount "5."`},
+		{in: "5. JPY", err: `invalid amount "5."`},
+		{in: "1.5 JPY", err: "JPY allows at most 0 decimal places"},
+		{in: "99999999999999999999 EUR", err: "invalid amount"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseMoney(tt.in)
+			if tt.err != "" {
+				wantErr(t, err, tt.err)
+				return
+			}
+			mustOK(t, err)
+			if got != tt.want {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	tests := map[string]Money{
+		"125.50 EUR": {Amount: 12550, Currency: "EUR", Decimals: 2},
+		"0.05 EUR":   {Amount: 5, Currency: "EUR", Decimals: 2},
+		"-0.05 EUR":  {Amount: -5, Currency: "EUR", Decimals: 2},
+		"1000 JPY":   {Amount: 1000, Currency: "JPY"},
+		"0.001 KWD":  {Amount: 1, Currency: "KWD", Decimals: 3},
+	}
+	for want, m := range tests {
+		if got := m.String(); got != want {
+			t.Errorf("String() = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestMoneyUnmarshalLegacy(t *testing.T) {
+	var asset Asset
+	mustOK(t, json.Unmarshal([]byte(`{"id":"asset1","owner":"Alice","value":300}`), &asset))
+	if asset.Value != (Money{Amount: 300, Currency: legacyCurrency}) {
+		t.Fatalf("legacy value = %+v", asset.Value)
+	}
+
+	var m Money
+	mustOK(t, json.Unmarshal([]byte(`{"amount":12550,"currency":"EUR","decimals":2}`), &m))
+	if m.String() != "125.50 EUR" {
+		t.Fatalf("value = %s", m)
+	}
+	wantErr(t, json.Unmarshal([]byte(`"125 EUR"`), &m), "legacy value")
+}
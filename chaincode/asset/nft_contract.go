@@ -0,0 +1,334 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	approvalObjectType = "nftapproval"
+	operatorObjectType = "nftoperator"
+)
+
+// NFTContract exposes assets through an ERC-721 style interface. Token IDs
+// are asset IDs and owners are client identities as returned by
+// ClientAccountID.
+type NFTContract struct {
+	contractapi.Contract
+
+	// BaseURI is prepended to token IDs by TokenURI.
+	BaseURI string
+
+	assets *AssetContract
+}
+
+// NewNFTContract returns an NFT contract operating on the records of assets.
+func NewNFTContract(assets *AssetContract) *NFTContract {
+	return &NFTContract{assets: assets}
+}
+
+// TransferEvent follows the ERC-721 Transfer event. Actor is the identity
+// that moved the token, which differs from From when an approved identity or
+// an operator did.
+type TransferEvent struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	TokenID string `json:"tokenId"`
+	Actor   string `json:"actor"`
+}
+
+type ApprovalEvent struct {
+	Owner    string `json:"owner"`
+	Approved string `json:"approved"`
+	TokenID  string `json:"tokenId"`
+}
+
+type ApprovalForAllEvent struct {
+	Owner    string `json:"owner"`
+	Operator string `json:"operator"`
+	Approved bool   `json:"approved"`
+}
+
+// ClientAccountID returns the account ID of the invoking identity.
+func (n *NFTContract) ClientAccountID(ctx contractapi.TransactionContextInterface) (string, error) {
+	return submitterID(ctx)
+}
+
+func (n *NFTContract) BalanceOf(ctx contractapi.TransactionContextInterface, owner string) (int, error) {
+	owner, err := validOwner(ctx, "owner", owner)
+	if err != nil {
+		return 0, err
+	}
+
+	iter, err := ctx.GetStub().GetStateByPartialCompositeKey(ownerIndex, []string{owner})
+	if err != nil {
+		return 0, fmt.Errorf("owner query: %w", err)
+	}
+	defer iter.Close()
+
+	balance := 0
+	for iter.HasNext() {
+		if _, err := iter.Next(); err != nil {
+			return 0, fmt.Errorf("iter next: %w", err)
+		}
+		balance++
+	}
+	return balance, nil
+}
+
+func (n *NFTContract) OwnerOf(ctx contractapi.TransactionContextInterface, tokenID string) (string, error) {
+	asset, err := n.assets.ReadAsset(ctx, tokenID)
+	if err != nil {
+		return "", err
+	}
+	return asset.Owner, nil
+}
+
+func (n *NFTContract) GetApproved(ctx contractapi.TransactionContextInterface, tokenID string) (string, error) {
+	asset, err := n.assets.ReadAsset(ctx, tokenID)
+	if err != nil {
+		return "", err
+	}
+	return readApproval(ctx, asset.ID)
+}
+
+func (n *NFTContract) IsApprovedForAll(ctx contractapi.TransactionContextInterface, owner string, operator string) (bool, error) {
+	owner, err := validOwner(ctx, "owner", owner)
+	if err != nil {
+		return false, err
+	}
+	if operator, err = validOwner(ctx, "operator", operator); err != nil {
+		return false, err
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(operatorObjectType, []string{owner, operator})
+	if err != nil {
+		return false, fmt.Errorf("create operator key: %w", err)
+	}
+	b, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return false, fmt.Errorf("get state: %w", err)
+	}
+	return b != nil, nil
+}
+
+// TokenURI returns BaseURI followed by the token ID, or a URN naming the
+// channel and asset when no BaseURI is configured.
+func (n *NFTContract) TokenURI(ctx contractapi.TransactionContextInterface, tokenID string) (string, error) {
+	asset, err := n.assets.ReadAsset(ctx, tokenID)
+	if err != nil {
+		return "", err
+	}
+	if n.BaseURI != "" {
+		return n.BaseURI + asset.ID, nil
+	}
+	return fmt.Sprintf("urn:fabric:%s:asset:%s", ctx.GetStub().GetChannelID(), asset.ID), nil
+}
+
+// TransferFrom moves a token from its owner to another identity. The invoker
+// must be the owner, the token's approved identity, or an operator of the
+// owner.
+func (n *NFTContract) TransferFrom(ctx contractapi.TransactionContextInterface, from string, to string, tokenID string) error {
+	guard, err := beginRequest(ctx, "TransferFrom", from, to, tokenID)
+	if err != nil {
+		return err
+	}
+	if _, ok := guard.replayed(); ok {
+		return nil
+	}
+
+	if err := requireOwnerMSP(ctx); err != nil {
+		return err
+	}
+
+	if from, err = validOwner(ctx, "from", from); err != nil {
+		return err
+	}
+	if to, err = validOwner(ctx, "to", to); err != nil {
+		return err
+	}
+
+	asset, err := n.assets.ReadAsset(ctx, tokenID)
+	if err != nil {
+		return err
+	}
+	if asset.Owner != from {
+		return fmt.Errorf("token %s is not owned by %s", asset.ID, from)
+	}
+
+	sender, err := submitterID(ctx)
+	if err != nil {
+		return err
+	}
+	approved, err := readApproval(ctx, asset.ID)
+	if err != nil {
+		return err
+	}
+	operator, err := n.IsApprovedForAll(ctx, from, sender)
+	if err != nil {
+		return err
+	}
+	if sender != from && sender != approved && !operator {
+		// Operators delegated the transfer right by the owner act like
+		// token operators.
+		operator, err = actsFor(ctx, sender, from, asset.ID, RightTransfer)
+		if err != nil {
+			return err
+		}
+	}
+	if sender != from && sender != approved && !operator {
+		return fmt.Errorf("%s is not the owner, approved or an operator of token %s", sender, asset.ID)
+	}
+
+	if err := n.assets.transferAsset(ctx, "TransferFrom", asset, to); err != nil {
+		return err
+	}
+	if err := setEvent(ctx, "Transfer", TransferEvent{From: from, To: to, TokenID: asset.ID, Actor: sender}); err != nil {
+		return err
+	}
+	return guard.complete(ctx, "")
+}
+
+// Approve lets approved transfer the token until the token changes owner.
+// Passing an empty approved revokes the current approval.
+func (n *NFTContract) Approve(ctx contractapi.TransactionContextInterface, approved string, tokenID string) error {
+	guard, err := beginRequest(ctx, "Approve", approved, tokenID)
+	if err != nil {
+		return err
+	}
+	if _, ok := guard.replayed(); ok {
+		return nil
+	}
+
+	if err := requireOwnerMSP(ctx); err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(approved) == "" {
+		approved = ""
+	} else if approved, err = validOwner(ctx, "approved", approved); err != nil {
+		return err
+	}
+
+	asset, err := n.assets.ReadAsset(ctx, tokenID)
+	if err != nil {
+		return err
+	}
+	sender, err := submitterID(ctx)
+	if err != nil {
+		return err
+	}
+	operator, err := n.IsApprovedForAll(ctx, asset.Owner, sender)
+	if err != nil {
+		return err
+	}
+	if sender != asset.Owner && !operator {
+		return fmt.Errorf("%s is not the owner or an operator of token %s", sender, asset.ID)
+	}
+	if approved == asset.Owner {
+		return errors.New("the owner cannot be approved for their own token")
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(approvalObjectType, []string{asset.ID})
+	if err != nil {
+		return fmt.Errorf("create approval key: %w", err)
+	}
+	if approved == "" {
+		err = ctx.GetStub().DelState(key)
+	} else {
+		err = ctx.GetStub().PutState(key, []byte(approved))
+	}
+	if err != nil {
+		return fmt.Errorf("update approval: %w", err)
+	}
+
+	if err := setEvent(ctx, "Approval", ApprovalEvent{Owner: asset.Owner, Approved: approved, TokenID: asset.ID}); err != nil {
+		return err
+	}
+	return guard.complete(ctx, "")
+}
+
+// SetApprovalForAll lets operator transfer and approve all tokens of the
+// invoker, now and in the future.
+func (n *NFTContract) SetApprovalForAll(ctx contractapi.TransactionContextInterface, operator string, approved bool) error {
+	guard, err := beginRequest(ctx, "SetApprovalForAll", operator, fmt.Sprint(approved))
+	if err != nil {
+		return err
+	}
+	if _, ok := guard.replayed(); ok {
+		return nil
+	}
+
+	if err := requireOwnerMSP(ctx); err != nil {
+		return err
+	}
+
+	if operator, err = validOwner(ctx, "operator", operator); err != nil {
+		return err
+	}
+	sender, err := submitterID(ctx)
+	if err != nil {
+		return err
+	}
+	if operator == sender {
+		return errors.New("cannot set approval for yourself")
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(operatorObjectType, []string{sender, operator})
+	if err != nil {
+		return fmt.Errorf("create operator key: %w", err)
+	}
+	if approved {
+		err = ctx.GetStub().PutState(key, []byte{0x01})
+	} else {
+		err = ctx.GetStub().DelState(key)
+	}
+	if err != nil {
+		return fmt.Errorf("update operator: %w", err)
+	}
+
+	if err := setEvent(ctx, "ApprovalForAll", ApprovalForAllEvent{Owner: sender, Operator: operator, Approved: approved}); err != nil {
+		return err
+	}
+	return guard.complete(ctx, "")
+}
+
+func readApproval(ctx contractapi.TransactionContextInterface, tokenID string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(approvalObjectType, []string{tokenID})
+	if err != nil {
+		return "", fmt.Errorf("create approval key: %w", err)
+	}
+	b, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return "", fmt.Errorf("get state: %w", err)
+	}
+	return string(b), nil
+}
+
+// clearApproval removes the single-token approval of an asset.
+func clearApproval(ctx contractapi.TransactionContextInterface, tokenID string) error {
+	key, err := ctx.GetStub().CreateCompositeKey(approvalObjectType, []string{tokenID})
+	if err != nil {
+		return fmt.Errorf("create approval key: %w", err)
+	}
+	if err := ctx.GetStub().DelState(key); err != nil {
+		return fmt.Errorf("delete approval: %w", err)
+	}
+	return nil
+}
+
+// setEvent emits a chaincode event with a JSON payload. Fabric keeps only the
+// last event set in a transaction.
+func setEvent(ctx contractapi.TransactionContextInterface, name string, payload interface{}) error {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal %s event: %w", name, err)
+	}
+	if err := ctx.GetStub().SetEvent(name, b); err != nil {
+		return fmt.Errorf("set %s event: %w", name, err)
+	}
+	return nil
+}
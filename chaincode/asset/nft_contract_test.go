@@ -0,0 +1,90 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestNFTTransferFrom(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("token1", alice.id(), "10 EUR")
+	e.createAsset("token2", alice.id(), "10 EUR")
+	e.verifyOwners(bob.id(), carol.id())
+
+	owner, err := e.nft.OwnerOf(e.tx(bob), "token1")
+	mustOK(t, err)
+	if owner != alice.id() {
+		t.Fatalf("owner = %s", owner)
+	}
+	balance, err := e.nft.BalanceOf(e.tx(bob), alice.id())
+	mustOK(t, err)
+	if balance != 2 {
+		t.Fatalf("balance = %d, want 2", balance)
+	}
+
+	wantErr(t, e.nft.TransferFrom(e.tx(alice), bob.id(), carol.id(), "token1"), "is not owned by")
+	wantErr(t, e.nft.TransferFrom(e.tx(bob), alice.id(), bob.id(), "token1"), "is not the owner, approved or an operator")
+
+	// A single-token approval lasts until the token changes owner.
+	mustOK(t, e.nft.Approve(e.tx(alice), bob.id(), "token1"))
+	approved, err := e.nft.GetApproved(e.tx(carol), "token1")
+	mustOK(t, err)
+	if approved != bob.id() {
+		t.Fatalf("approved = %s", approved)
+	}
+	ctx := e.tx(bob)
+	mustOK(t, e.nft.TransferFrom(ctx, alice.id(), carol.id(), "token1"))
+	var ev TransferEvent
+	if name := eventOf(t, ctx, &ev); name != "Transfer" || ev.To != carol.id() {
+		t.Fatalf("event %s %+v", name, ev)
+	}
+	if approved, _ := e.nft.GetApproved(e.tx(carol), "token1"); approved != "" {
+		t.Fatalf("approval kept after transfer: %s", approved)
+	}
+
+	// Operators may transfer every token of the owner.
+	mustOK(t, e.nft.SetApprovalForAll(e.tx(alice), bob.id(), true))
+	mustOK(t, e.nft.TransferFrom(e.tx(bob), alice.id(), bob.id(), "token2"))
+	mustOK(t, e.nft.SetApprovalForAll(e.tx(alice), bob.id(), false))
+	ok, err := e.nft.IsApprovedForAll(e.tx(carol), alice.id(), bob.id())
+	mustOK(t, err)
+	if ok {
+		t.Fatal("operator approval not revoked")
+	}
+	wantErr(t, e.nft.SetApprovalForAll(e.tx(alice), alice.id(), true), "cannot set approval for yourself")
+	wantErr(t, e.nft.Approve(e.tx(alice), bob.id(), "token1"), "is not the owner or an operator")
+	wantErr(t, e.nft.Approve(e.tx(carol), carol.id(), "token1"), "cannot be approved for their own token")
+}
+
+func TestNFTTransferFromHonorsDelegation(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("token1", alice.id(), "10 EUR")
+	e.verifyOwners(carol.id())
+
+	mustOK(t, e.assets.GrantOperator(e.tx(alice), bob.id(), "token1", []string{RightTransfer}, ""))
+	ctx := e.tx(bob)
+	mustOK(t, e.nft.TransferFrom(ctx, alice.id(), carol.id(), "token1"))
+	if got := e.readAsset("token1").Owner; got != carol.id() {
+		t.Fatalf("owner = %s", got)
+	}
+	var ev TransferEvent
+	if name := eventOf(t, ctx, &ev); name != "Transfer" || ev.From != alice.id() || ev.Actor != bob.id() {
+		t.Fatalf("event %s %+v", name, ev)
+	}
+}
+
+func TestNFTTokenURI(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("token1", alice.id(), "10 EUR")
+
+	uri, err := e.nft.TokenURI(e.tx(bob), "token1")
+	mustOK(t, err)
+	if uri != "urn:fabric:mychannel:asset:token1" {
+		t.Fatalf("uri = %s", uri)
+	}
+	e.nft.BaseURI = "https://assets.example.com/"
+	uri, err = e.nft.TokenURI(e.tx(bob), "token1")
+	mustOK(t, err)
+	if uri != "https://assets.example.com/token1" {
+		t.Fatalf("uri = %s", uri)
+	}
+}
@@ -0,0 +1,413 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	ownerProfileObjectType = "ownerprofile"
+	personalDataObjectType = "ownerpii"
+
+	// personalDataTransientKey carries {"name": "Alice Example"}.
+	personalDataTransientKey = "personal"
+
+	KYCPending   = "pending"
+	KYCVerified  = "verified"
+	KYCRejected  = "rejected"
+	KYCSuspended = "suspended"
+	// KYCErased marks the tombstone left by ErasePersonalData. It cannot be
+	// set with SetKYCStatus.
+	KYCErased = "erased"
+)
+
+var kycStatuses = []string{KYCPending, KYCVerified, KYCRejected, KYCSuspended}
+
+var errOwnerNotVerified = errors.New("owner not verified")
+
+// OwnerProfile registers an owner string, either a label or a client
+// identity, so that assets can only be held by known, KYC-verified owners.
+// The personal data of the owner is kept in DataCollection, never in the
+// public profile; Name is only filled in when GetOwnerProfile is invoked by
+// an org holding it.
+type OwnerProfile struct {
+	Owner        string `json:"owner"`
+	Name         string `json:"name,omitempty" metadata:",optional"`
+	Jurisdiction string `json:"jurisdiction,omitempty" metadata:",optional"`
+	KYCStatus    string `json:"kycStatus"`
+	RegisteredBy string `json:"registeredBy"`
+	RegisteredAt string `json:"registeredAt"`
+	ReviewedBy   string `json:"reviewedBy,omitempty" metadata:",optional"`
+	ReviewedAt   string `json:"reviewedAt,omitempty" metadata:",optional"`
+	Note         string `json:"note,omitempty" metadata:",optional"`
+	// DataCollection is the personal data collection of the registering org
+	// that holds the PersonalData of the owner.
+	DataCollection string `json:"dataCollection,omitempty" metadata:",optional"`
+	ErasedBy       string `json:"erasedBy,omitempty" metadata:",optional"`
+	ErasedAt       string `json:"erasedAt,omitempty" metadata:",optional"`
+}
+
+// PersonalData is the personal information of an owner. It is passed as
+// transient data and stored only in a private data collection, so that it
+// can be purged on request.
+type PersonalData struct {
+	Owner string `json:"owner"`
+	Name  string `json:"name"`
+}
+
+// OwnerRegistryContract maintains the owner profiles checked by AssetContract
+// when assets are created or change owner.
+type OwnerRegistryContract struct {
+	contractapi.Contract
+
+	// ComplianceMSP is the MSP whose identities may set KYC statuses.
+	ComplianceMSP string
+}
+
+// RegisterOwner registers a new owner with a pending KYC status. The name of
+// the owner must be passed as transient data under "personal" and is stored
+// in the personal data collection of the invoker's org, which the compliance
+// org shares. Arguments are recorded in the block, out of reach of a purge,
+// so name must be left empty.
+func (r *OwnerRegistryContract) RegisterOwner(ctx contractapi.TransactionContextInterface, owner string, name string, jurisdiction string) error {
+	guard, err := beginRequest(ctx, "RegisterOwner", owner, name, jurisdiction)
+	if err != nil {
+		return err
+	}
+	if _, ok := guard.replayed(); ok {
+		return nil
+	}
+
+	if owner, err = validOwner(ctx, "owner", owner); err != nil {
+		return err
+	}
+	if strings.TrimSpace(name) != "" {
+		return fmt.Errorf("name must be passed as transient data under %q, not as an argument", personalDataTransientKey)
+	}
+	personal, err := transientPersonalData(ctx)
+	if err != nil {
+		return err
+	}
+	personal.Owner = owner
+
+	existing, err := readOwnerProfile(ctx, owner)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("owner %s is already registered", owner)
+	}
+
+	now, err := txTimeRFC3339(ctx)
+	if err != nil {
+		return err
+	}
+	registeredBy, err := submitterID(ctx)
+	if err != nil {
+		return err
+	}
+
+	collection, err := putPersonalData(ctx, personal)
+	if err != nil {
+		return err
+	}
+
+	profile := &OwnerProfile{
+		Owner:          owner,
+		Jurisdiction:   strings.TrimSpace(jurisdiction),
+		KYCStatus:      KYCPending,
+		RegisteredBy:   registeredBy,
+		RegisteredAt:   now,
+		DataCollection: collection,
+	}
+	if err := putOwnerProfile(ctx, profile); err != nil {
+		return err
+	}
+	return guard.complete(ctx, "")
+}
+
+// SetKYCStatus records the outcome of a KYC review of a registered owner.
+func (r *OwnerRegistryContract) SetKYCStatus(ctx contractapi.TransactionContextInterface, owner string, status string, note string) error {
+	guard, err := beginRequest(ctx, "SetKYCStatus", owner, status, note)
+	if err != nil {
+		return err
+	}
+	if _, ok := guard.replayed(); ok {
+		return nil
+	}
+
+	if err := r.requireCompliance(ctx); err != nil {
+		return err
+	}
+	if !contains(kycStatuses, status) {
+		return fmt.Errorf("unknown KYC status %q, expected one of %s", status, strings.Join(kycStatuses, ", "))
+	}
+
+	profile, err := r.GetOwnerProfile(ctx, owner)
+	if err != nil {
+		return err
+	}
+	if profile.KYCStatus == KYCErased {
+		return fmt.Errorf("owner %s has been erased", profile.Owner)
+	}
+
+	now, err := txTimeRFC3339(ctx)
+	if err != nil {
+		return err
+	}
+	reviewer, err := submitterID(ctx)
+	if err != nil {
+		return err
+	}
+
+	profile.KYCStatus = status
+	profile.ReviewedBy = reviewer
+	profile.ReviewedAt = now
+	profile.Note = strings.TrimSpace(note)
+	if err := putOwnerProfile(ctx, profile); err != nil {
+		return err
+	}
+	return guard.complete(ctx, "")
+}
+
+// GetOwnerProfile returns the profile of a registered owner. The name is
+// included when the invoker's org holds the personal data of the owner.
+func (r *OwnerRegistryContract) GetOwnerProfile(ctx contractapi.TransactionContextInterface, owner string) (*OwnerProfile, error) {
+	owner, err := validOwner(ctx, "owner", owner)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := readOwnerProfile(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	if profile == nil {
+		return nil, fmt.Errorf("owner %s is not registered", owner)
+	}
+	holds, err := r.holdsPersonalData(ctx, profile)
+	if err != nil {
+		return nil, err
+	}
+	if profile.KYCStatus != KYCErased && holds {
+		personal, err := readPersonalData(ctx, profile)
+		if err != nil {
+			return nil, err
+		}
+		if personal != nil {
+			profile.Name = personal.Name
+		}
+	}
+	return profile, nil
+}
+
+// GetOwnerPersonalData returns the personal data of a registered owner. Only
+// peers of the registering org and of the compliance org hold the data, so
+// the query must be sent to one of them.
+func (r *OwnerRegistryContract) GetOwnerPersonalData(ctx contractapi.TransactionContextInterface, owner string) (*PersonalData, error) {
+	profile, err := r.GetOwnerProfile(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	if profile.KYCStatus == KYCErased {
+		return nil, fmt.Errorf("owner %s has been erased", profile.Owner)
+	}
+	if profile.DataCollection == "" {
+		return nil, fmt.Errorf("owner %s has no private personal data", profile.Owner)
+	}
+	p, err := readPersonalData(ctx, profile)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, fmt.Errorf("personal data of owner %s is not held in %s on this peer", profile.Owner, profile.DataCollection)
+	}
+	return p, nil
+}
+
+// ListOwnerProfiles returns all registered owners, optionally only those
+// with the given KYC status.
+func (r *OwnerRegistryContract) ListOwnerProfiles(ctx contractapi.TransactionContextInterface, status string) ([]*OwnerProfile, error) {
+	iter, err := ctx.GetStub().GetStateByPartialCompositeKey(ownerProfileObjectType, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("owner profile query: %w", err)
+	}
+	defer iter.Close()
+
+	out := []*OwnerProfile{}
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return nil, fmt.Errorf("iter next: %w", err)
+		}
+		var p OwnerProfile
+		if err := json.Unmarshal(kv.Value, &p); err != nil {
+			return nil, fmt.Errorf("unmarshal owner profile: %w", err)
+		}
+		if status == "" || p.KYCStatus == status {
+			out = append(out, &p)
+		}
+	}
+	return out, nil
+}
+
+func (r *OwnerRegistryContract) requireCompliance(ctx contractapi.TransactionContextInterface) error {
+	return requireComplianceMSP(ctx, r.ComplianceMSP)
+}
+
+// requireComplianceMSP rejects invokers outside the compliance MSP, which
+// defaults to the regulator MSP.
+func requireComplianceMSP(ctx contractapi.TransactionContextInterface, compliance string) error {
+	if compliance == "" {
+		compliance = defaultRegulatorMSP
+	}
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("get msp id: %w", err)
+	}
+	if mspID != compliance {
+		return fmt.Errorf("only %s identities may set KYC statuses", compliance)
+	}
+	return nil
+}
+
+// requireVerifiedOwner fails with errOwnerNotVerified unless owner is
+// registered with a verified KYC status.
+func requireVerifiedOwner(ctx contractapi.TransactionContextInterface, owner string) error {
+	profile, err := readOwnerProfile(ctx, owner)
+	if err != nil {
+		return err
+	}
+	if profile == nil {
+		return fmt.Errorf("%w: owner %s is not registered", errOwnerNotVerified, owner)
+	}
+	if profile.KYCStatus != KYCVerified {
+		return fmt.Errorf("%w: owner %s has KYC status %s", errOwnerNotVerified, owner, profile.KYCStatus)
+	}
+	return nil
+}
+
+func readOwnerProfile(ctx contractapi.TransactionContextInterface, owner string) (*OwnerProfile, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(ownerProfileObjectType, []string{owner})
+	if err != nil {
+		return nil, fmt.Errorf("create owner profile key: %w", err)
+	}
+	b, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("get state: %w", err)
+	}
+	if b == nil {
+		return nil, nil
+	}
+	var p OwnerProfile
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, fmt.Errorf("unmarshal owner profile: %w", err)
+	}
+	return &p, nil
+}
+
+// personalDataCollection returns the collection holding the personal data
+// of the owners registered by mspID. Its members are mspID and the compliance
+// org, which must be able to purge the data; see collections_config.json.
+func personalDataCollection(mspID string) string {
+	return "personalData" + mspID
+}
+
+// holdsPersonalData reports whether the invoker's org is a member of the
+// collection holding the personal data of the owner of profile.
+func (r *OwnerRegistryContract) holdsPersonalData(ctx contractapi.TransactionContextInterface, profile *OwnerProfile) (bool, error) {
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return false, fmt.Errorf("get msp id: %w", err)
+	}
+	if profile.DataCollection == personalDataCollection(mspID) {
+		return true, nil
+	}
+	return r.requireCompliance(ctx) == nil, nil
+}
+
+// putPersonalData stores p in the personal data collection of the invoker's
+// org and returns the collection name.
+func putPersonalData(ctx contractapi.TransactionContextInterface, p *PersonalData) (string, error) {
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("get msp id: %w", err)
+	}
+	collection := personalDataCollection(mspID)
+	key, err := ctx.GetStub().CreateCompositeKey(personalDataObjectType, []string{p.Owner})
+	if err != nil {
+		return "", fmt.Errorf("create personal data key: %w", err)
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		return "", fmt.Errorf("marshal personal data: %w", err)
+	}
+	if err := ctx.GetStub().PutPrivateData(collection, key, b); err != nil {
+		return "", fmt.Errorf("put private data: %w", err)
+	}
+	return collection, nil
+}
+
+// readPersonalData returns the personal data of the owner of profile, or nil
+// when this peer does not hold it.
+func readPersonalData(ctx contractapi.TransactionContextInterface, profile *OwnerProfile) (*PersonalData, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(personalDataObjectType, []string{profile.Owner})
+	if err != nil {
+		return nil, fmt.Errorf("create personal data key: %w", err)
+	}
+	b, err := ctx.GetStub().GetPrivateData(profile.DataCollection, key)
+	if err != nil {
+		return nil, fmt.Errorf("get private data: %w", err)
+	}
+	if b == nil {
+		return nil, nil
+	}
+	var p PersonalData
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, fmt.Errorf("unmarshal personal data: %w", err)
+	}
+	return &p, nil
+}
+
+// transientPersonalData decodes the personal data passed as transient data.
+func transientPersonalData(ctx contractapi.TransactionContextInterface) (*PersonalData, error) {
+	transient, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return nil, fmt.Errorf("get transient: %w", err)
+	}
+	raw, ok := transient[personalDataTransientKey]
+	if !ok || len(raw) == 0 {
+		return nil, fmt.Errorf("personal data must be passed as transient data under %q", personalDataTransientKey)
+	}
+	var p PersonalData
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return nil, fmt.Errorf("unmarshal personal data: %w", err)
+	}
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return nil, errors.New("name is required")
+	}
+	return &p, nil
+}
+
+// putOwnerProfile stores the public profile. The name, which GetOwnerProfile
+// may have filled in, is left out.
+func putOwnerProfile(ctx contractapi.TransactionContextInterface, p *OwnerProfile) error {
+	key, err := ctx.GetStub().CreateCompositeKey(ownerProfileObjectType, []string{p.Owner})
+	if err != nil {
+		return fmt.Errorf("create owner profile key: %w", err)
+	}
+	public := *p
+	public.Name = ""
+	b, err := json.Marshal(public)
+	if err != nil {
+		return fmt.Errorf("marshal owner profile: %w", err)
+	}
+	if err := ctx.GetStub().PutState(key, b); err != nil {
+		return fmt.Errorf("put state: %w", err)
+	}
+	return nil
+}
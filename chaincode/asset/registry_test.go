@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRegisterOwner(t *testing.T) {
+	e := newTestEnv(t)
+
+	wantErr(t, e.registerOwner(alice, " ", "Alice", ""), "owner is required")
+	wantErr(t, e.registerOwner(alice, "Alice", " ", ""), "name is required")
+	mustOK(t, e.registerOwner(alice, " Alice ", "Alice Example", "NL"))
+	wantErr(t, e.registerOwner(bob, "Alice", "Someone Else", ""), "owner Alice is already registered")
+
+	profile, err := e.registry.GetOwnerProfile(e.tx(bob), "Alice")
+	mustOK(t, err)
+	if profile.KYCStatus != KYCPending || profile.RegisteredBy != alice.id() || profile.Jurisdiction != "NL" {
+		t.Fatalf("unexpected profile %+v", profile)
+	}
+	_, err = e.registry.GetOwnerProfile(e.tx(bob), "Bob")
+	wantErr(t, err, "owner Bob is not registered")
+}
+
+func TestRegisterOwnerKeepsNamePrivate(t *testing.T) {
+	e := newTestEnv(t)
+
+	// Arguments end up in the block, so names are only accepted as transient data.
+	wantErr(t, e.registry.RegisterOwner(e.tx(alice), "Alice", "Alice Example", "NL"), `name must be passed as transient data under "personal", not as an argument`)
+	wantErr(t, e.registry.RegisterOwner(e.tx(alice), "Alice", "", "NL"), `personal data must be passed as transient data under "personal"`)
+	mustOK(t, e.registerOwner(alice, "Alice", "Alice Example", "NL"))
+	mustOK(t, e.registerOwner(bob, "Bob", "Bob Example", "US"))
+	mustOK(t, e.registry.SetKYCStatus(e.tx(regulator), "Alice", KYCVerified, ""))
+	e.ledger.commit()
+	for key, value := range e.ledger.state {
+		if strings.Contains(string(value), "Alice Example") || strings.Contains(string(value), "Bob Example") {
+			t.Fatalf("name stored publicly under %q", key)
+		}
+	}
+
+	// Only the org holding the personal data sees the name.
+	profile, err := e.registry.GetOwnerProfile(e.tx(carol), "Alice")
+	mustOK(t, err)
+	if profile.Name != "Alice Example" || profile.KYCStatus != KYCVerified {
+		t.Fatalf("unexpected profile %+v", profile)
+	}
+	profile, err = e.registry.GetOwnerProfile(e.tx(alice), "Bob")
+	mustOK(t, err)
+	if profile.Name != "" {
+		t.Fatalf("name of Bob visible to Org1: %+v", profile)
+	}
+	profile, err = e.registry.GetOwnerProfile(e.tx(bob), "Bob")
+	mustOK(t, err)
+	if profile.Name != "Bob Example" {
+		t.Fatalf("unexpected profile %+v", profile)
+	}
+}
+
+func TestSetKYCStatus(t *testing.T) {
+	e := newTestEnv(t)
+	mustOK(t, e.registerOwner(alice, "Alice", "Alice Example", ""))
+	mustOK(t, e.registerOwner(bob, "Bob", "Bob Example", ""))
+
+	wantErr(t, e.registry.SetKYCStatus(e.tx(alice), "Alice", KYCVerified, ""), "only Org3MSP identities may set KYC statuses")
+	wantErr(t, e.registry.SetKYCStatus(e.tx(regulator), "Alice", "approved", ""), `unknown KYC status "approved"`)
+	wantErr(t, e.registry.SetKYCStatus(e.tx(regulator), "Carol", KYCVerified, ""), "owner Carol is not registered")
+	mustOK(t, e.registry.SetKYCStatus(e.tx(regulator), "Alice", KYCVerified, "passport checked"))
+
+	profile, err := e.registry.GetOwnerProfile(e.tx(bob), "Alice")
+	mustOK(t, err)
+	if profile.ReviewedBy != regulator.id() || profile.Note != "passport checked" {
+		t.Fatalf("unexpected profile %+v", profile)
+	}
+
+	verified, err := e.registry.ListOwnerProfiles(e.tx(bob), KYCVerified)
+	mustOK(t, err)
+	if len(verified) != 1 || verified[0].Owner != "Alice" {
+		t.Fatalf("verified = %+v", verified)
+	}
+	all, err := e.registry.ListOwnerProfiles(e.tx(bob), "")
+	mustOK(t, err)
+	if len(all) != 2 {
+		t.Fatalf("got %d profiles, want 2", len(all))
+	}
+
+	e.registry.ComplianceMSP = "Org2MSP"
+	mustOK(t, e.registry.SetKYCStatus(e.tx(bob), "Bob", KYCRejected, ""))
+}
+
+func TestAssetsRequireVerifiedOwners(t *testing.T) {
+	e := newTestEnv(t)
+
+	err := e.assets.CreateAsset(e.tx(alice), "asset1", "Alice", "10 EUR")
+	if !errors.Is(err, errOwnerNotVerified) {
+		t.Fatalf("err = %v, want errOwnerNotVerified", err)
+	}
+	wantErr(t, err, "owner Alice is not registered")
+
+	mustOK(t, e.registerOwner(alice, "Alice", "Alice Example", ""))
+	wantErr(t, e.assets.CreateAsset(e.tx(alice), "asset1", "Alice", "10 EUR"), "owner Alice has KYC status pending")
+
+	mustOK(t, e.registry.SetKYCStatus(e.tx(regulator), "Alice", KYCVerified, ""))
+	e.verifyOwners("Bob")
+	e.createAsset("asset1", "Alice", "10 EUR")
+	mustOK(t, e.registry.SetKYCStatus(e.tx(regulator), "Bob", KYCSuspended, ""))
+	wantErr(t, e.assets.UpdateAssetOwner(e.tx(alice), "asset1", "Bob"), "owner Bob has KYC status suspended")
+}
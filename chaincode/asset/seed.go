@@ -0,0 +1,155 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const seedObjectType = "seed"
+
+// seedData is the dataset loaded by InitLedger.
+//
+//go:embed fixtures/seed.json
+var seedData []byte
+
+// seedDataset lists the assets to create and their owners, which InitLedger
+// registers as KYC verified so that the assets can change hands like any
+// other.
+type seedDataset struct {
+	Owners []seedOwner `json:"owners"`
+	Assets []seedAsset `json:"assets"`
+}
+
+type seedOwner struct {
+	Owner        string `json:"owner"`
+	Jurisdiction string `json:"jurisdiction"`
+}
+
+type seedAsset struct {
+	ID    string `json:"id"`
+	Owner string `json:"owner"`
+	Value string `json:"value"`
+}
+
+type seedMarker struct {
+	TxID     string `json:"txId"`
+	SeededAt string `json:"seededAt"`
+	Count    int    `json:"count"`
+}
+
+// InitLedger creates the assets of the embedded seed dataset and registers
+// their owners as KYC verified, unless they are already registered. As it
+// verifies owners, only admins of the compliance org may seed. It can be used
+// as the --isInit invocation and refuses to run a second time, except for
+// retries of the same request ID.
+func (c *AssetContract) InitLedger(ctx contractapi.TransactionContextInterface) error {
+	guard, err := beginRequest(ctx, "InitLedger")
+	if err != nil {
+		return err
+	}
+	if _, ok := guard.replayed(); ok {
+		return nil
+	}
+	if err := requireComplianceMSP(ctx, c.ComplianceMSP); err != nil {
+		return err
+	}
+	if err := requireOrgAdmin(ctx); err != nil {
+		return err
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(seedObjectType, []string{})
+	if err != nil {
+		return fmt.Errorf("create seed key: %w", err)
+	}
+	b, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("get state: %w", err)
+	}
+	if b != nil {
+		var marker seedMarker
+		if err := json.Unmarshal(b, &marker); err != nil {
+			return fmt.Errorf("unmarshal seed marker: %w", err)
+		}
+		return fmt.Errorf("ledger was already seeded in tx %s", marker.TxID)
+	}
+
+	var data seedDataset
+	if err := json.Unmarshal(seedData, &data); err != nil {
+		return fmt.Errorf("unmarshal seed data: %w", err)
+	}
+	if len(data.Assets) == 0 {
+		return errors.New("seed data is empty")
+	}
+
+	now, err := txTimeRFC3339(ctx)
+	if err != nil {
+		return err
+	}
+	invoker, err := submitterID(ctx)
+	if err != nil {
+		return err
+	}
+	// Reads do not see the writes of the same transaction, so duplicates
+	// within the dataset are tracked here.
+	owners := make(map[string]bool)
+	for _, o := range data.Owners {
+		if owners[o.Owner] {
+			return fmt.Errorf("seed owner %s is listed twice", o.Owner)
+		}
+		owners[o.Owner] = true
+		if err := seedOwnerProfile(ctx, o, invoker, now); err != nil {
+			return err
+		}
+	}
+	ids := make(map[string]bool)
+	for _, a := range data.Assets {
+		if ids[a.ID] {
+			return fmt.Errorf("seed asset %s is listed twice", a.ID)
+		}
+		ids[a.ID] = true
+		if !owners[a.Owner] {
+			return fmt.Errorf("seed asset %s: owner %s is not a seed owner", a.ID, a.Owner)
+		}
+		if err := c.createAsset(ctx, "InitLedger", a.ID, a.Owner, a.Value); err != nil {
+			return fmt.Errorf("seed asset %s: %w", a.ID, err)
+		}
+	}
+
+	b, err = json.Marshal(seedMarker{TxID: ctx.GetStub().GetTxID(), SeededAt: now, Count: len(data.Assets)})
+	if err != nil {
+		return fmt.Errorf("marshal seed marker: %w", err)
+	}
+	if err := ctx.GetStub().PutState(key, b); err != nil {
+		return fmt.Errorf("put state: %w", err)
+	}
+	return guard.complete(ctx, "")
+}
+
+// seedOwnerProfile registers a seed owner as KYC verified. Owners registered
+// before keep their profile, which must be verified already.
+func seedOwnerProfile(ctx contractapi.TransactionContextInterface, o seedOwner, invoker string, now string) error {
+	profile, err := readOwnerProfile(ctx, o.Owner)
+	if err != nil {
+		return err
+	}
+	if profile != nil {
+		if profile.KYCStatus != KYCVerified {
+			return fmt.Errorf("seed owner %s is registered with KYC status %s", o.Owner, profile.KYCStatus)
+		}
+		return nil
+	}
+	return putOwnerProfile(ctx, &OwnerProfile{
+		Owner:        o.Owner,
+		Jurisdiction: o.Jurisdiction,
+		KYCStatus:    KYCVerified,
+		RegisteredBy: invoker,
+		RegisteredAt: now,
+		ReviewedBy:   invoker,
+		ReviewedAt:   now,
+		Note:         "seed data",
+	})
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestInitLedger(t *testing.T) {
+	e := newTestEnv(t)
+
+	// Seeding verifies owners, so only compliance admins may seed.
+	wantErr(t, e.assets.InitLedger(e.tx(org1Admin)), "only Org3MSP identities")
+	wantErr(t, e.assets.InitLedger(e.tx(regulator)), "only org admin identities")
+
+	ctx := e.tx(org3Admin).withTransient(requestIDKey, "seed-1")
+	mustOK(t, e.assets.InitLedger(ctx))
+	assets, err := e.assets.GetAllAssets(e.tx(alice))
+	mustOK(t, err)
+	if len(assets) != 6 {
+		t.Fatalf("seeded %d assets, want 6", len(assets))
+	}
+
+	// A retry of the same request replays instead of failing.
+	mustOK(t, e.assets.InitLedger(e.tx(org3Admin).withTransient(requestIDKey, "seed-1")))
+	err = e.assets.InitLedger(e.tx(org3Admin))
+	wantErr(t, err, "ledger was already seeded in tx "+ctx.stub.txID)
+
+	// Seed owners are verified, so seeded assets can change hands.
+	profile, err := e.registry.GetOwnerProfile(e.tx(bob), "Charlie")
+	mustOK(t, err)
+	if profile.KYCStatus != KYCVerified || profile.Jurisdiction != "GB" {
+		t.Fatalf("profile %+v", profile)
+	}
+	mustOK(t, e.assets.UpdateAssetOwner(e.tx(alice), "seed-asset-001", "Bob"))
+}
+
+func TestInitLedgerKeepsRegisteredOwners(t *testing.T) {
+	e := newTestEnv(t)
+	mustOK(t, e.registerOwner(alice, "Bob", "Bob Example", "NL"))
+
+	wantErr(t, e.assets.InitLedger(e.tx(org3Admin)), "seed owner Bob is registered with KYC status pending")
+	mustOK(t, e.registry.SetKYCStatus(e.tx(regulator), "Bob", KYCVerified, ""))
+	mustOK(t, e.assets.InitLedger(e.tx(org3Admin)))
+	if profile, err := e.registry.GetOwnerProfile(e.tx(bob), "Bob"); err != nil || profile.RegisteredBy != alice.id() {
+		t.Fatalf("profile %+v, err %v", profile, err)
+	}
+}
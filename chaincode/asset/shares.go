@@ -0,0 +1,300 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	shareConsentObjectType = "shareconsent"
+	// wholeAsset is 100% in basis points.
+	wholeAsset = 10000
+)
+
+// ShareTransfer is a proposed move of basis points between two holders. It is
+// applied once both holders have consented.
+type ShareTransfer struct {
+	AssetID     string   `json:"assetId"`
+	From        string   `json:"from"`
+	To          string   `json:"to"`
+	BasisPoints int      `json:"basisPoints"`
+	Consents    []string `json:"consents"`
+	Applied     bool     `json:"applied"`
+}
+
+type SharePosition struct {
+	AssetID     string `json:"assetId"`
+	BasisPoints int    `json:"basisPoints"`
+}
+
+// TransferShares moves basisPoints of an asset from one holder to another.
+// Both holders must invoke it with the same arguments; the first call records
+// the invoker's consent and the second one applies the transfer.
+func (c *AssetContract) TransferShares(ctx contractapi.TransactionContextInterface, id string, from string, to string, basisPoints int) (*ShareTransfer, error) {
+	guard, err := beginRequest(ctx, "TransferShares", id, from, to, strconv.Itoa(basisPoints))
+	if err != nil {
+		return nil, err
+	}
+	if result, ok := guard.replayed(); ok {
+		var transfer ShareTransfer
+		if err := json.Unmarshal([]byte(result), &transfer); err != nil {
+			return nil, fmt.Errorf("unmarshal share transfer: %w", err)
+		}
+		return &transfer, nil
+	}
+
+	if err := requireOwnerMSP(ctx); err != nil {
+		return nil, err
+	}
+
+	if from, err = validOwner(ctx, "from", from); err != nil {
+		return nil, err
+	}
+	if to, err = validOwner(ctx, "to", to); err != nil {
+		return nil, err
+	}
+	if from == to {
+		return nil, errors.New("from and to must differ")
+	}
+	if basisPoints <= 0 || basisPoints > wholeAsset {
+		return nil, fmt.Errorf("basisPoints must be between 1 and %d", wholeAsset)
+	}
+
+	asset, err := c.ReadAsset(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireVerifiedOwner(ctx, to); err != nil {
+		return nil, err
+	}
+	if err := checkNotFrozen(ctx, asset.ID); err != nil {
+		return nil, err
+	}
+	if err := checkNoOpenDispute(ctx, asset.ID); err != nil {
+		return nil, err
+	}
+	if asset.ParentID != "" {
+		return nil, fmt.Errorf("asset %s is a component of %s and cannot be held in shares", asset.ID, asset.ParentID)
+	}
+	children, err := componentIDs(ctx, asset.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(children) > 0 {
+		return nil, fmt.Errorf("asset %s has components and cannot be held in shares", asset.ID)
+	}
+
+	if err := checkLeaseAllows(ctx, asset.ID, true); err != nil {
+		return nil, err
+	}
+
+	shares, err := moveShares(holdings(asset), from, to, basisPoints)
+	if err != nil {
+		return nil, fmt.Errorf("asset %s: %w", asset.ID, err)
+	}
+
+	invoker, err := submitterID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(shareConsentObjectType, []string{asset.ID, from, to, strconv.Itoa(basisPoints)})
+	if err != nil {
+		return nil, fmt.Errorf("create consent key: %w", err)
+	}
+	transfer := &ShareTransfer{AssetID: asset.ID, From: from, To: to, BasisPoints: basisPoints, Consents: []string{}}
+	b, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("get state: %w", err)
+	}
+	if b != nil {
+		if err := json.Unmarshal(b, transfer); err != nil {
+			return nil, fmt.Errorf("unmarshal share transfer: %w", err)
+		}
+	}
+	party, operator, err := consentingParty(ctx, invoker, asset.ID, transfer)
+	if err != nil {
+		return nil, err
+	}
+	if !contains(transfer.Consents, party) {
+		transfer.Consents = append(transfer.Consents, party)
+	}
+
+	if contains(transfer.Consents, from) && contains(transfer.Consents, to) {
+		now, err := txTimeRFC3339(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		before := *asset
+		asset.Owner = shares[0].Holder
+		asset.Shares = shares
+		if len(shares) == 1 {
+			asset.Shares = nil
+		}
+		asset.UpdatedAt = now
+		asset.Version++
+
+		if err := c.saveAsset(ctx, "TransferShares", &before, asset); err != nil {
+			return nil, err
+		}
+		if before.Owner != asset.Owner {
+			if err := clearApproval(ctx, asset.ID); err != nil {
+				return nil, err
+			}
+		}
+		if err := ctx.GetStub().DelState(key); err != nil {
+			return nil, fmt.Errorf("delete state: %w", err)
+		}
+		transfer.Applied = true
+	} else {
+		b, err := json.Marshal(transfer)
+		if err != nil {
+			return nil, fmt.Errorf("marshal share transfer: %w", err)
+		}
+		if err := ctx.GetStub().PutState(key, b); err != nil {
+			return nil, fmt.Errorf("put state: %w", err)
+		}
+	}
+
+	if err := emitOperation(ctx, "TransferShares", asset, party, &actor{id: invoker, operator: operator}); err != nil {
+		return nil, err
+	}
+
+	result, err := json.Marshal(transfer)
+	if err != nil {
+		return nil, fmt.Errorf("marshal share transfer: %w", err)
+	}
+	if err := guard.complete(ctx, string(result)); err != nil {
+		return nil, err
+	}
+	return transfer, nil
+}
+
+// GetSharePositions lists the assets a holder has a share in, including
+// assets the holder owns outright.
+func (c *AssetContract) GetSharePositions(ctx contractapi.TransactionContextInterface, holder string) ([]*SharePosition, error) {
+	holder, err := validOwner(ctx, "holder", holder)
+	if err != nil {
+		return nil, err
+	}
+
+	iter, err := ctx.GetStub().GetStateByPartialCompositeKey(holderIndex, []string{holder})
+	if err != nil {
+		return nil, fmt.Errorf("holder query: %w", err)
+	}
+	defer iter.Close()
+
+	out := []*SharePosition{}
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return nil, fmt.Errorf("iter next: %w", err)
+		}
+		_, attrs, err := ctx.GetStub().SplitCompositeKey(kv.Key)
+		if err != nil {
+			return nil, fmt.Errorf("split index key: %w", err)
+		}
+		asset, err := readAsset(ctx, attrs[1])
+		if err != nil {
+			return nil, err
+		}
+		for _, share := range holdings(asset) {
+			if share.Holder == holder {
+				out = append(out, &SharePosition{AssetID: asset.ID, BasisPoints: share.BasisPoints})
+			}
+		}
+	}
+	return out, nil
+}
+
+// consentingParty returns the holder of transfer the invoker consents for,
+// with the same rules as authorize: a holder consents for itself, an operator
+// holding the transfer right for the holder that delegated it, and any
+// invoker for a label holder, which carries no identity. Holders that have
+// not consented yet are preferred.
+func consentingParty(ctx contractapi.TransactionContextInterface, invoker string, assetID string, transfer *ShareTransfer) (string, bool, error) {
+	holders := []string{transfer.From, transfer.To}
+	if contains(holders, invoker) {
+		return invoker, false, nil
+	}
+	var party string
+	operator := false
+	for _, holder := range holders {
+		ok := !isIdentity(holder)
+		delegated := false
+		if !ok {
+			var err error
+			if delegated, err = actsFor(ctx, invoker, holder, assetID, RightTransfer); err != nil {
+				return "", false, err
+			}
+			ok = delegated
+		}
+		if !ok {
+			continue
+		}
+		if party == "" || contains(transfer.Consents, party) {
+			party, operator = holder, delegated
+		}
+	}
+	if party == "" {
+		return "", false, errors.New("only the holders giving or receiving shares may consent to a transfer")
+	}
+	return party, operator, nil
+}
+
+// checkNotShared fails while asset is held in shares. The owner is only the
+// largest holder then and may not act alone on the whole asset.
+func checkNotShared(asset *Asset) error {
+	if len(asset.Shares) > 0 {
+		return fmt.Errorf("asset %s is held in shares and its largest holder may not act alone on it", asset.ID)
+	}
+	return nil
+}
+
+// holdings returns the shares of an asset, treating an asset without shares
+// as wholly held by its owner.
+func holdings(asset *Asset) []*OwnershipShare {
+	if len(asset.Shares) == 0 {
+		return []*OwnershipShare{{Holder: asset.Owner, BasisPoints: wholeAsset}}
+	}
+	return asset.Shares
+}
+
+// moveShares returns a new share list with basisPoints moved from one holder
+// to another, without empty holdings and sorted by size, largest first.
+func moveShares(shares []*OwnershipShare, from string, to string, basisPoints int) ([]*OwnershipShare, error) {
+	byHolder := make(map[string]int)
+	total := 0
+	for _, share := range shares {
+		byHolder[share.Holder] += share.BasisPoints
+		total += share.BasisPoints
+	}
+	if total != wholeAsset {
+		return nil, fmt.Errorf("shares sum to %d basis points instead of %d", total, wholeAsset)
+	}
+	if byHolder[from] < basisPoints {
+		return nil, fmt.Errorf("%s holds %d basis points, fewer than %d", from, byHolder[from], basisPoints)
+	}
+	byHolder[from] -= basisPoints
+	byHolder[to] += basisPoints
+
+	var out []*OwnershipShare
+	for holder, bp := range byHolder {
+		if bp > 0 {
+			out = append(out, &OwnershipShare{Holder: holder, BasisPoints: bp})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].BasisPoints != out[j].BasisPoints {
+			return out[i].BasisPoints > out[j].BasisPoints
+		}
+		return out[i].Holder < out[j].Holder
+	})
+	return out, nil
+}
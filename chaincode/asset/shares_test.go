@@ -0,0 +1,120 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTransferShares(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("house", alice.id(), "500000 EUR")
+	e.verifyOwners(bob.id())
+
+	transfer, err := e.assets.TransferShares(e.tx(alice), "house", alice.id(), bob.id(), 4000)
+	mustOK(t, err)
+	if transfer.Applied || len(transfer.Consents) != 1 {
+		t.Fatalf("applied before both consented: %+v", transfer)
+	}
+	if len(e.readAsset("house").Shares) != 0 {
+		t.Fatal("shares moved before both consented")
+	}
+
+	transfer, err = e.assets.TransferShares(e.tx(bob), "house", alice.id(), bob.id(), 4000)
+	mustOK(t, err)
+	if !transfer.Applied {
+		t.Fatalf("not applied: %+v", transfer)
+	}
+	house := e.readAsset("house")
+	if house.Owner != alice.id() || len(house.Shares) != 2 || house.Shares[0].BasisPoints != 6000 {
+		t.Fatalf("unexpected asset %+v", house)
+	}
+
+	positions, err := e.assets.GetSharePositions(e.tx(carol), bob.id())
+	mustOK(t, err)
+	if len(positions) != 1 || positions[0].BasisPoints != 4000 {
+		t.Fatalf("unexpected positions %+v", positions)
+	}
+
+	// Whole-asset transfers of shared assets must go through shares.
+	wantErr(t, e.assets.UpdateAssetOwner(e.tx(alice), "house", bob.id()), "is held in shares")
+
+	// The largest holder becomes the owner.
+	for _, id := range []*mockIdentity{alice, bob} {
+		_, err = e.assets.TransferShares(e.tx(id), "house", alice.id(), bob.id(), 3000)
+		mustOK(t, err)
+	}
+	house = e.readAsset("house")
+	if house.Owner != bob.id() {
+		t.Fatalf("owner = %s, want bob", house.Owner)
+	}
+}
+
+func TestTransferSharesErrors(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("house", alice.id(), "500000 EUR")
+	e.verifyOwners(bob.id())
+
+	tests := []struct {
+		name     string
+		invoker  *mockIdentity
+		from, to string
+		bp       int
+		err      string
+	}{
+		{"missing holder", alice, "", bob.id(), 10, "from is required"},
+		{"same holder", alice, alice.id(), alice.id(), 10, "from and to must differ"},
+		{"too many basis points", alice, alice.id(), bob.id(), 10001, "basisPoints must be between 1 and 10000"},
+		{"unverified receiver", alice, alice.id(), carol.id(), 10, "not registered"},
+		{"more than held", bob, bob.id(), alice.id(), 10, "fewer than 10"},
+		{"outsider", carol, alice.id(), bob.id(), 10, "only the holders giving or receiving shares"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := e.assets.TransferShares(e.tx(tc.invoker), "house", tc.from, tc.to, tc.bp)
+			wantErr(t, err, tc.err)
+		})
+	}
+}
+
+func TestSharedAssetNeedsAllHolders(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("house", alice.id(), "500000 EUR")
+	e.verifyOwners(bob.id())
+	for _, id := range []*mockIdentity{alice, bob} {
+		_, err := e.assets.TransferShares(e.tx(id), "house", alice.id(), bob.id(), 4000)
+		mustOK(t, err)
+	}
+
+	now := e.ledger.clock
+	start := now.Add(time.Hour).Format(time.RFC3339)
+	end := now.Add(48 * time.Hour).Format(time.RFC3339)
+	const msg = "asset house is held in shares and its largest holder may not act alone on it"
+	wantErr(t, e.assets.DeleteAsset(e.tx(alice), "house"), msg)
+	wantErr(t, e.assets.UpdateAssetValue(e.tx(alice), "house", "1 EUR"), msg)
+	wantErr(t, e.assets.LeaseAsset(e.tx(alice), "house", carol.id(), start, end, LeaseTerms{}), msg)
+	wantErr(t, e.assets.CreateAuction(e.tx(alice), "auction1", "house", "EUR"), msg)
+}
+
+func TestTransferSharesBetweenLabels(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("barn", "Alice", "80000 EUR")
+	e.verifyOwners("Bob")
+
+	// Label holders carry no identity, so any client consents for them, one
+	// holder per call.
+	transfer, err := e.assets.TransferShares(e.tx(carol), "barn", "Alice", "Bob", 2500)
+	mustOK(t, err)
+	if transfer.Applied || len(transfer.Consents) != 1 || transfer.Consents[0] != "Alice" {
+		t.Fatalf("first consent %+v", transfer)
+	}
+	ctx := e.tx(carol)
+	transfer, err = e.assets.TransferShares(ctx, "barn", "Alice", "Bob", 2500)
+	mustOK(t, err)
+	if !transfer.Applied {
+		t.Fatalf("not applied: %+v", transfer)
+	}
+	var event OperationEvent
+	if name := eventOf(t, ctx, &event); name != "AssetOperation" || event.Owner != "Bob" || event.Actor != carol.id() || event.Operator {
+		t.Fatalf("event %s %+v", name, event)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// maxTags bounds the index entries written per asset.
+const maxTags = 32
+
+// SetAssetTags replaces the tags of an asset. Tags are free-form labels such
+// as "warehouse-b" or "insured"; pass an empty list to remove all tags.
+func (c *AssetContract) SetAssetTags(ctx contractapi.TransactionContextInterface, id string, tags []string) error {
+	guard, err := beginRequest(ctx, "SetAssetTags", id, strings.Join(tags, ","))
+	if err != nil {
+		return err
+	}
+	if _, ok := guard.replayed(); ok {
+		return nil
+	}
+
+	if err := requireOwnerMSP(ctx); err != nil {
+		return err
+	}
+
+	normalized, err := normalizeTags(tags)
+	if err != nil {
+		return err
+	}
+
+	asset, err := c.ReadAsset(ctx, id)
+	if err != nil {
+		return err
+	}
+	a, err := authorize(ctx, asset, RightUpdateValue)
+	if err != nil {
+		return err
+	}
+	if err := checkNotFrozen(ctx, asset.ID); err != nil {
+		return err
+	}
+
+	now, err := txTimeRFC3339(ctx)
+	if err != nil {
+		return err
+	}
+
+	before := *asset
+	asset.Tags = normalized
+	asset.UpdatedAt = now
+	asset.Version++
+
+	if err := c.saveAsset(ctx, "SetAssetTags", &before, asset); err != nil {
+		return err
+	}
+	if err := emitOperation(ctx, "SetAssetTags", asset, asset.Owner, a); err != nil {
+		return err
+	}
+	return guard.complete(ctx, "")
+}
+
+// normalizeTags lower-cases, deduplicates and sorts tags. It returns nil for
+// an empty list so that untagged assets omit the field.
+func normalizeTags(tags []string) ([]string, error) {
+	seen := make(map[string]struct{}, len(tags))
+	var out []string
+	for _, t := range tags {
+		tag := normalizeTag(t)
+		if tag == "" {
+			return nil, fmt.Errorf("tag %q is empty", t)
+		}
+		if _, ok := seen[tag]; ok {
+			continue
+		}
+		seen[tag] = struct{}{}
+		out = append(out, tag)
+	}
+	if len(out) > maxTags {
+		return nil, fmt.Errorf("at most %d tags are allowed, got %d", maxTags, len(out))
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
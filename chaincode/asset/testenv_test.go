@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// Identities used across the tests. Org3 is the default regulator and
+// compliance org.
+var (
+	alice     = newMockIdentity("Org1MSP", "alice", "client")
+	bob       = newMockIdentity("Org2MSP", "bob", "client")
+	carol     = newMockIdentity("Org1MSP", "carol", "client")
+	org1Admin = newMockIdentity("Org1MSP", "Admin@org1.example.com", "admin")
+	org3Admin = newMockIdentity("Org3MSP", "Admin@org3.example.com", "admin")
+	regulator = newMockIdentity("Org3MSP", "regulator", "client")
+)
+
+// testEnv wires the contracts to a fresh mock ledger.
+type testEnv struct {
+	t        *testing.T
+	ledger   *mockLedger
+	assets   *AssetContract
+	nft      *NFTContract
+	registry *OwnerRegistryContract
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	assets := &AssetContract{}
+	ledger := newMockLedger()
+	mustOK(t, ledger.loadCollections("collections_config.json"))
+	return &testEnv{
+		t:        t,
+		ledger:   ledger,
+		assets:   assets,
+		nft:      NewNFTContract(assets),
+		registry: &OwnerRegistryContract{},
+	}
+}
+
+// tx starts a new transaction invoked by identity.
+func (e *testEnv) tx(identity *mockIdentity) *mockContext {
+	return e.ledger.newTx(identity, "")
+}
+
+// registerOwner registers owner, passing name as transient personal data.
+func (e *testEnv) registerOwner(identity *mockIdentity, owner string, name string, jurisdiction string) error {
+	e.t.Helper()
+	personal, err := json.Marshal(PersonalData{Name: name})
+	mustOK(e.t, err)
+	return e.registry.RegisterOwner(e.tx(identity).withTransient(personalDataTransientKey, string(personal)), owner, "", jurisdiction)
+}
+
+// verifyOwners registers owners and marks them KYC verified.
+func (e *testEnv) verifyOwners(owners ...string) {
+	e.t.Helper()
+	for _, owner := range owners {
+		mustOK(e.t, e.registerOwner(alice, owner, "Owner "+owner, "NL"))
+		mustOK(e.t, e.registry.SetKYCStatus(e.tx(regulator), owner, KYCVerified, ""))
+	}
+}
+
+// createAsset creates an asset for a verified owner, invoked by alice.
+func (e *testEnv) createAsset(id string, owner string, value string) *Asset {
+	e.t.Helper()
+	if p, _ := readOwnerProfile(e.tx(alice), owner); p == nil {
+		e.verifyOwners(owner)
+	}
+	mustOK(e.t, e.assets.CreateAsset(e.tx(alice), id, owner, value))
+	return e.readAsset(id)
+}
+
+func (e *testEnv) readAsset(id string) *Asset {
+	e.t.Helper()
+	asset, err := e.assets.ReadAsset(e.tx(alice), id)
+	mustOK(e.t, err)
+	return asset
+}
+
+func mustOK(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// wantErr fails unless err contains substr.
+func wantErr(t *testing.T, err error, substr string) {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("expected error containing %q, got nil", substr)
+	}
+	if !strings.Contains(err.Error(), substr) {
+		t.Fatalf("expected error containing %q, got %q", substr, err)
+	}
+}
+
+// eventOf decodes the event set by the transaction of ctx into v and returns
+// its name.
+func eventOf(t *testing.T, ctx *mockContext, v interface{}) string {
+	t.Helper()
+	if ctx.stub.event == nil {
+		t.Fatal("no event set")
+	}
+	if err := json.Unmarshal(ctx.stub.event.Payload, v); err != nil {
+		t.Fatalf("unmarshal event: %v", err)
+	}
+	return ctx.stub.event.EventName
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// defaultIDCharset lists the characters besides ASCII letters and digits
+	// allowed in IDs unless ContractConfig.IDCharset says otherwise. "*" is
+	// never allowed, it stands for all assets in delegations.
+	defaultIDCharset      = "-_.:@"
+	defaultMaxIDLength    = 64
+	defaultMaxOwnerLength = 1024
+)
+
+// builtinReservedIDPrefixes may never start an ID: U+0000 starts every
+// composite key, so an ID starting with it could overwrite index entries and
+// other records, and CouchDB reserves document IDs starting with "_".
+var builtinReservedIDPrefixes = []string{"\x00", "_"}
+
+// normalizeID trims id and checks it against the ID rules of the config.
+// name is the argument name used in errors.
+func (cfg *ContractConfig) normalizeID(name string, id string) (string, error) {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return "", fmt.Errorf("%s is required", name)
+	}
+	maxLength := cfg.MaxIDLength
+	if maxLength == 0 {
+		maxLength = defaultMaxIDLength
+	}
+	if len(id) > maxLength {
+		return "", fmt.Errorf("%s is %d characters long, the maximum is %d", name, len(id), maxLength)
+	}
+	for _, prefix := range append(builtinReservedIDPrefixes, cfg.ReservedIDPrefixes...) {
+		if strings.HasPrefix(id, prefix) {
+			return "", fmt.Errorf("%s %q starts with the reserved prefix %q", name, id, prefix)
+		}
+	}
+	charset := cfg.IDCharset
+	if charset == "" {
+		charset = defaultIDCharset
+	}
+	for _, r := range id {
+		if r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune(charset, r)) {
+			return "", fmt.Errorf("%s %q contains %q; only letters, digits and %q are allowed", name, id, r, charset)
+		}
+	}
+	return id, nil
+}
+
+// normalizeOwner trims owner, converts it to Unicode normalization form C so
+// that equal looking owners are stored alike, and checks it against the
+// owner rules of the config. Owners are labels or client identities and may
+// contain any printable character.
+func (cfg *ContractConfig) normalizeOwner(name string, owner string) (string, error) {
+	if !utf8.ValidString(owner) {
+		return "", fmt.Errorf("%s is not valid UTF-8", name)
+	}
+	owner = norm.NFC.String(strings.TrimSpace(owner))
+	if owner == "" {
+		return "", fmt.Errorf("%s is required", name)
+	}
+	maxLength := cfg.MaxOwnerLength
+	if maxLength == 0 {
+		maxLength = defaultMaxOwnerLength
+	}
+	if n := utf8.RuneCountInString(owner); n > maxLength {
+		return "", fmt.Errorf("%s is %d characters long, the maximum is %d", name, n, maxLength)
+	}
+	for _, r := range owner {
+		if !unicode.IsPrint(r) {
+			return "", fmt.Errorf("%s %q contains the non-printable character %U", name, owner, r)
+		}
+	}
+	return owner, nil
+}
+
+// checkValidationRules rejects validation settings that would make IDs
+// ambiguous or every ID invalid.
+func (cfg *ContractConfig) checkValidationRules() error {
+	for _, r := range cfg.IDCharset {
+		if r > unicode.MaxASCII || !(unicode.IsPunct(r) || unicode.IsSymbol(r)) || r == '*' {
+			return fmt.Errorf("idCharset may only list ASCII punctuation and symbols other than \"*\", got %q", r)
+		}
+	}
+	if cfg.MaxIDLength < 0 {
+		return errors.New("maxIdLength must be >= 0")
+	}
+	if cfg.MaxOwnerLength < 0 {
+		return errors.New("maxOwnerLength must be >= 0")
+	}
+	for _, prefix := range cfg.ReservedIDPrefixes {
+		if prefix == "" {
+			return errors.New("reservedIdPrefixes must not contain an empty prefix")
+		}
+	}
+	return nil
+}
+
+// validID applies the ID rules of the stored config, see normalizeID.
+func validID(ctx contractapi.TransactionContextInterface, name string, id string) (string, error) {
+	cfg, err := readConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	return cfg.normalizeID(name, id)
+}
+
+// existingID trims the ID of a record to look up. Records may predate the
+// current ID rules, so reads and changes of existing records only reject IDs
+// no record can have: empty ones and ones starting with U+0000, which address
+// composite keys. New IDs go through validID.
+func existingID(name string, id string) (string, error) {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return "", fmt.Errorf("%s is required", name)
+	}
+	if strings.HasPrefix(id, "\x00") {
+		return "", fmt.Errorf("%s %q starts with the reserved prefix %q", name, id, "\x00")
+	}
+	return id, nil
+}
+
+// validOwner applies the owner rules of the stored config, see
+// normalizeOwner.
+func validOwner(ctx contractapi.TransactionContextInterface, name string, owner string) (string, error) {
+	cfg, err := readConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	return cfg.normalizeOwner(name, owner)
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeID(t *testing.T) {
+	cfg := &ContractConfig{}
+	tests := []struct {
+		in   string
+		want string
+		err  string
+	}{
+		{in: "  asset-1 ", want: "asset-1"},
+		{in: "org1:asset_2.v1@eu", want: "org1:asset_2.v1@eu"},
+		{in: " ", err: "id is required"},
+		{in: "a/b", err: `id "a/b" contains '/'; only letters, digits and "-_.:@" are allowed`},
+		{in: "a b", err: "contains ' '"},
+		{in: "café", err: "contains 'é'"},
+		{in: "a\x01b", err: `contains '\x01'`},
+		{in: "*", err: "contains '*'"},
+		{in: "\x00freeze\x00asset1\x00", err: `starts with the reserved prefix "\x00"`},
+		{in: "_design", err: `starts with the reserved prefix "_"`},
+		{in: strings.Repeat("a", 65), err: "id is 65 characters long, the maximum is 64"},
+	}
+	for _, tt := range tests {
+		got, err := cfg.normalizeID("id", tt.in)
+		if tt.err != "" {
+			wantErr(t, err, tt.err)
+			continue
+		}
+		mustOK(t, err)
+		if got != tt.want {
+			t.Errorf("normalizeID(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	cfg = &ContractConfig{IDCharset: "-/", MaxIDLength: 8, ReservedIDPrefixes: []string{"sys-"}}
+	for in, errText := range map[string]string{
+		"a/b":       "",
+		"a.b":       "contains '.'; only letters, digits and \"-/\" are allowed",
+		"sys-1":     `starts with the reserved prefix "sys-"`,
+		"123456789": "the maximum is 8",
+	} {
+		_, err := cfg.normalizeID("id", in)
+		if errText == "" {
+			mustOK(t, err)
+		} else {
+			wantErr(t, err, errText)
+		}
+	}
+}
+
+func TestNormalizeOwner(t *testing.T) {
+	cfg := &ContractConfig{}
+
+	// "é" as e followed by a combining acute accent becomes U+00E9.
+	got, err := cfg.normalizeOwner("owner", " Jose\u0301 Example ")
+	mustOK(t, err)
+	if got != "Jos\u00e9 Example" {
+		t.Fatalf("got %q", got)
+	}
+	got, err = cfg.normalizeOwner("owner", alice.id())
+	mustOK(t, err)
+	if got != alice.id() {
+		t.Fatalf("identity changed to %q", got)
+	}
+
+	_, err = cfg.normalizeOwner("owner", "")
+	wantErr(t, err, "owner is required")
+	_, err = cfg.normalizeOwner("newOwner", "Bob\nSmith")
+	wantErr(t, err, `newOwner "Bob\nSmith" contains the non-printable character U+000A`)
+	_, err = cfg.normalizeOwner("owner", "Bob\u200b")
+	wantErr(t, err, "non-printable character U+200B")
+	_, err = cfg.normalizeOwner("owner", "\xff")
+	wantErr(t, err, "owner is not valid UTF-8")
+	_, err = cfg.normalizeOwner("owner", strings.Repeat("x", 1<<20))
+	wantErr(t, err, "owner is 1048576 characters long, the maximum is 1024")
+
+	cfg.MaxOwnerLength = 3
+	_, err = cfg.normalizeOwner("owner", "Bob")
+	mustOK(t, err)
+	_, err = cfg.normalizeOwner("owner", "Carl")
+	wantErr(t, err, "the maximum is 3")
+}
+
+func TestSetConfigValidationRules(t *testing.T) {
+	e := newTestEnv(t)
+
+	wantErr(t, e.assets.SetConfig(e.tx(org3Admin), ContractConfig{IDCharset: "-*"}), `other than "*", got '*'`)
+	wantErr(t, e.assets.SetConfig(e.tx(org3Admin), ContractConfig{IDCharset: "x"}), "got 'x'")
+	wantErr(t, e.assets.SetConfig(e.tx(org3Admin), ContractConfig{MaxIDLength: -1}), "maxIdLength must be >= 0")
+	wantErr(t, e.assets.SetConfig(e.tx(org3Admin), ContractConfig{MaxOwnerLength: -1}), "maxOwnerLength must be >= 0")
+	wantErr(t, e.assets.SetConfig(e.tx(org3Admin), ContractConfig{ReservedIDPrefixes: []string{""}}), "must not contain an empty prefix")
+
+	mustOK(t, e.assets.SetConfig(e.tx(org3Admin), ContractConfig{ReservedIDPrefixes: []string{"asset-"}}))
+	e.verifyOwners("Alice")
+	wantErr(t, e.assets.CreateAsset(e.tx(alice), "asset-1", "Alice", "1 EUR"), `id "asset-1" starts with the reserved prefix "asset-"`)
+	_, err := e.assets.CreateAssetAuto(e.tx(alice), "Alice", "1 EUR")
+	wantErr(t, err, "starts with the reserved prefix")
+}
+
+// TestValidationAppliesEverywhere checks that functions taking new IDs or
+// owners reject invalid ones before touching the ledger.
+func TestValidationAppliesEverywhere(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("asset1", alice.id(), "10 EUR")
+	badID := "../asset1"
+	badOwner := "Mallory\x00"
+
+	checks := map[string]error{
+		"CreateAsset":          e.assets.CreateAsset(e.tx(alice), badID, "Alice", "1 EUR"),
+		"CreateAsset owner":    e.assets.CreateAsset(e.tx(alice), "asset2", badOwner, "1 EUR"),
+		"UpdateAssetOwner":     e.assets.UpdateAssetOwner(e.tx(alice), "asset1", badOwner),
+		"GrantOperator":        e.assets.GrantOperator(e.tx(alice), badOwner, "asset1", []string{RightTransfer}, ""),
+		"LeaseAsset":           e.assets.LeaseAsset(e.tx(alice), "asset1", badOwner, "2024-01-01T00:00:00Z", "2025-01-01T00:00:00Z", LeaseTerms{}),
+		"CreateAuction":        e.assets.CreateAuction(e.tx(alice), badID, "asset1", "EUR"),
+		"SetApprovalForAll":    e.nft.SetApprovalForAll(e.tx(alice), badOwner, true),
+		"TransferFrom":         e.nft.TransferFrom(e.tx(alice), alice.id(), badOwner, "asset1"),
+		"RegisterOwner":        e.registerOwner(alice, badOwner, "Mallory", ""),
+		"SetKYCStatus":         e.registry.SetKYCStatus(e.tx(regulator), badOwner, KYCVerified, ""),
+		"ResolveDispute":       e.assets.ResolveDispute(e.tx(regulator), "asset1", "decision", badOwner),
+		"CreateAssetCommitted": e.assets.CreateAssetCommitted(e.tx(alice), badID, "Alice"),
+	}
+	_, err := e.assets.GetDelegations(e.tx(bob), badOwner)
+	checks["GetDelegations"] = err
+	_, err = e.assets.GetSharePositions(e.tx(bob), badOwner)
+	checks["GetSharePositions"] = err
+	_, err = e.assets.TransferShares(e.tx(alice), "asset1", alice.id(), badOwner, 10)
+	checks["TransferShares"] = err
+	_, err = e.assets.HasUsageRight(e.tx(bob), "asset1", badOwner)
+	checks["HasUsageRight"] = err
+	_, err = e.nft.BalanceOf(e.tx(bob), badOwner)
+	checks["BalanceOf"] = err
+	_, err = e.registry.GetOwnerProfile(e.tx(bob), badOwner)
+	checks["GetOwnerProfile"] = err
+
+	for fn, err := range checks {
+		if err == nil || !(strings.Contains(err.Error(), `contains '/'`) || strings.Contains(err.Error(), `starts with the reserved prefix`) || strings.Contains(err.Error(), "non-printable character U+0000")) {
+			t.Errorf("%s: err = %v, want a validation error", fn, err)
+		}
+	}
+}
+
+// TestLegacyIDsStayReachable checks that records stored before the current ID
+// rules can still be read, changed and deleted.
+func TestLegacyIDsStayReachable(t *testing.T) {
+	e := newTestEnv(t)
+	e.verifyOwners("Alice")
+	legacy, err := json.Marshal(Asset{ID: "legacy/1", Owner: "Alice", Value: Money{Amount: 500, Currency: "EUR", Decimals: 2}, Version: 1, CreatedAt: "2023-01-01T00:00:00Z", UpdatedAt: "2023-01-01T00:00:00Z"})
+	mustOK(t, err)
+	mustOK(t, e.tx(alice).stub.PutState("legacy/1", legacy))
+
+	wantErr(t, e.assets.CreateAsset(e.tx(alice), "legacy/2", "Alice", "1 EUR"), `contains '/'`)
+	exists, err := e.assets.AssetExists(e.tx(bob), " legacy/1 ")
+	mustOK(t, err)
+	if !exists {
+		t.Fatal("legacy asset not found")
+	}
+	mustOK(t, e.assets.UpdateAssetValue(e.tx(alice), "legacy/1", "6 EUR"))
+	if v := e.readAsset("legacy/1").Value.String(); v != "6.00 EUR" {
+		t.Fatalf("value = %s", v)
+	}
+	trail, err := e.assets.GetAuditTrail(e.tx(bob), "legacy/1", 10, "")
+	mustOK(t, err)
+	if len(trail.Records) != 1 {
+		t.Fatalf("got %d audit records, want 1", len(trail.Records))
+	}
+	mustOK(t, e.assets.DeleteAsset(e.tx(alice), "legacy/1"))
+
+	_, err = e.assets.ReadAsset(e.tx(bob), "\x00asset")
+	wantErr(t, err, "starts with the reserved prefix")
+	_, err = e.assets.ReadAsset(e.tx(bob), " ")
+	wantErr(t, err, "id is required")
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	valuationObjectType = "valuation"
+
+	// appraiserAttribute is the enrollment certificate attribute, issued by
+	// the CA of an appraiser org, that marks identities allowed to submit
+	// valuations.
+	appraiserAttribute = "appraiser"
+
+	ValuationPending  = "pending"
+	ValuationAccepted = "accepted"
+	ValuationRejected = "rejected"
+
+	effectiveDateLayout = "2006-01-02"
+)
+
+// Valuation is an appraisal of an asset. Accepted valuations form the value
+// history of the asset; the one with the latest effective time is its
+// current value.
+type Valuation struct {
+	ID           string `json:"id"`
+	AssetID      string `json:"assetId"`
+	Value        Money  `json:"value"`
+	Method       string `json:"method"`
+	EffectiveAt  string `json:"effectiveAt"`
+	Appraiser    string `json:"appraiser"`
+	AppraiserMSP string `json:"appraiserMsp"`
+	SubmittedAt  string `json:"submittedAt"`
+	Status       string `json:"status"`
+	ReviewedBy   string `json:"reviewedBy,omitempty" metadata:",optional"`
+	// ReviewedFor is the owner an operator reviewed the valuation for.
+	ReviewedFor string `json:"reviewedFor,omitempty" metadata:",optional"`
+	ReviewedAt  string `json:"reviewedAt,omitempty" metadata:",optional"`
+	Note        string `json:"note,omitempty" metadata:",optional"`
+}
+
+// SubmitValuation records a pending appraisal of an asset and returns its ID.
+// Only identities of the appraiser MSPs whose certificate carries the
+// appraiser=true attribute may submit, and never for assets they hold.
+// effectiveDate is an RFC 3339 timestamp or a date, and may not lie after the
+// transaction time.
+func (c *AssetContract) SubmitValuation(ctx contractapi.TransactionContextInterface, assetID string, value string, method string, effectiveDate string) (string, error) {
+	guard, err := beginRequest(ctx, "SubmitValuation", assetID, value, method, effectiveDate)
+	if err != nil {
+		return "", err
+	}
+	if result, ok := guard.replayed(); ok {
+		return result, nil
+	}
+
+	if err := c.requireAppraiser(ctx); err != nil {
+		return "", err
+	}
+	cfg, err := readConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	amount, err := parseValue("value", value)
+	if err != nil {
+		return "", err
+	}
+	if err := cfg.checkValue("value", amount); err != nil {
+		return "", err
+	}
+	method = strings.TrimSpace(method)
+	if method == "" {
+		return "", errors.New("method is required")
+	}
+	effectiveAt, err := parseEffectiveDate(effectiveDate)
+	if err != nil {
+		return "", err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return "", err
+	}
+	if effectiveAt.After(now) {
+		return "", fmt.Errorf("effectiveDate %s is in the future", effectiveAt.Format(time.RFC3339Nano))
+	}
+
+	asset, err := c.ReadAsset(ctx, assetID)
+	if err != nil {
+		return "", err
+	}
+	if concealed(asset) {
+		return "", fmt.Errorf("asset %s has a concealed value", asset.ID)
+	}
+	appraiser, err := submitterID(ctx)
+	if err != nil {
+		return "", err
+	}
+	if holdsAsset(asset, appraiser) {
+		return "", fmt.Errorf("appraisers may not value asset %s, which they hold", asset.ID)
+	}
+	appraiserMSP, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("get msp id: %w", err)
+	}
+
+	valuation := &Valuation{
+		ID:           ctx.GetStub().GetTxID(),
+		AssetID:      asset.ID,
+		Value:        amount,
+		Method:       method,
+		EffectiveAt:  effectiveAt.Format(indexTimeLayout),
+		Appraiser:    appraiser,
+		AppraiserMSP: appraiserMSP,
+		SubmittedAt:  now.Format(time.RFC3339Nano),
+		Status:       ValuationPending,
+	}
+	// Keys sort by effective time, so GetValuations lists the series in
+	// order and the last accepted entry is the current value.
+	key, err := ctx.GetStub().CreateCompositeKey(valuationObjectType, []string{asset.ID, valuation.EffectiveAt, valuation.ID})
+	if err != nil {
+		return "", fmt.Errorf("create valuation key: %w", err)
+	}
+	if err := putValuation(ctx, key, valuation); err != nil {
+		return "", err
+	}
+	if err := recordRelatedAudit(ctx, "SubmitValuation", asset.ID, "valuation", nil, valuation); err != nil {
+		return "", err
+	}
+	if err := setEvent(ctx, "ValuationSubmitted", valuation); err != nil {
+		return "", err
+	}
+	if err := guard.complete(ctx, valuation.ID); err != nil {
+		return "", err
+	}
+	return valuation.ID, nil
+}
+
+// ReviewValuation accepts or rejects a pending valuation of an asset. Anyone
+// who may update the value of the asset may review, except the appraiser.
+// An accepted valuation becomes the value of the asset unless an accepted
+// valuation with a later effective time exists.
+func (c *AssetContract) ReviewValuation(ctx contractapi.TransactionContextInterface, assetID string, valuationID string, accept bool, note string) error {
+	guard, err := beginRequest(ctx, "ReviewValuation", assetID, valuationID, fmt.Sprint(accept), note)
+	if err != nil {
+		return err
+	}
+	if _, ok := guard.replayed(); ok {
+		return nil
+	}
+
+	cfg, err := readConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if err := cfg.checkOwnerMSP(ctx); err != nil {
+		return err
+	}
+	asset, err := c.ReadAsset(ctx, assetID)
+	if err != nil {
+		return err
+	}
+	a, err := authorize(ctx, asset, RightUpdateValue)
+	if err != nil {
+		return err
+	}
+	if err := checkNotShared(asset); err != nil {
+		return err
+	}
+
+	valuations, keys, err := readValuations(ctx, asset.ID)
+	if err != nil {
+		return err
+	}
+	idx := -1
+	for i, v := range valuations {
+		if v.ID == strings.TrimSpace(valuationID) {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return fmt.Errorf("valuation %s of asset %s does not exist", valuationID, asset.ID)
+	}
+	valuation, key := valuations[idx], keys[idx]
+	pending := *valuation
+	if valuation.Status != ValuationPending {
+		return fmt.Errorf("valuation %s is already %s", valuation.ID, valuation.Status)
+	}
+	reviewer := a.id
+	if reviewer == valuation.Appraiser {
+		return fmt.Errorf("valuation %s cannot be reviewed by its appraiser", valuation.ID)
+	}
+
+	now, err := txTimeRFC3339(ctx)
+	if err != nil {
+		return err
+	}
+	valuation.Status = ValuationRejected
+	if accept {
+		valuation.Status = ValuationAccepted
+	}
+	valuation.ReviewedBy = reviewer
+	if a.operator {
+		valuation.ReviewedFor = asset.Owner
+	}
+	valuation.ReviewedAt = now
+	valuation.Note = strings.TrimSpace(note)
+	if err := putValuation(ctx, key, valuation); err != nil {
+		return err
+	}
+
+	// Keys sort by effective time, so the valuation is current when no
+	// accepted valuation sorts after it.
+	current := accept
+	for _, v := range valuations[idx+1:] {
+		if v.Status == ValuationAccepted {
+			current = false
+			break
+		}
+	}
+	if current {
+		if err := checkNotFrozen(ctx, asset.ID); err != nil {
+			return err
+		}
+		if concealed(asset) {
+			return fmt.Errorf("asset %s has a concealed value; use CommitAssetValue or RevealValue", asset.ID)
+		}
+		before := *asset
+		asset.Value = valuation.Value
+		asset.ValuationID = valuation.ID
+		asset.ValueCommitment = ""
+		asset.ValueSalt = ""
+		asset.ValueCollection = ""
+		asset.UpdatedAt = now
+		asset.Version++
+		if err := c.saveAsset(ctx, "ReviewValuation", &before, asset); err != nil {
+			return err
+		}
+	} else if err := recordRelatedAudit(ctx, "ReviewValuation", asset.ID, "valuation", &pending, valuation); err != nil {
+		return err
+	}
+	if err := setEvent(ctx, "ValuationReviewed", valuation); err != nil {
+		return err
+	}
+	return guard.complete(ctx, "")
+}
+
+// GetValuations returns the valuations of an asset, pending and reviewed,
+// ordered by effective time.
+func (c *AssetContract) GetValuations(ctx contractapi.TransactionContextInterface, id string) ([]*Valuation, error) {
+	id, err := existingID("id", id)
+	if err != nil {
+		return nil, err
+	}
+	valuations, _, err := readValuations(ctx, id)
+	return valuations, err
+}
+
+// readValuations returns the valuations of an asset and their keys, ordered
+// by effective time.
+// checkNotAppraised rejects direct value changes of an asset with valuations.
+// Once appraised, the value of an asset only changes through reviewed
+// valuations.
+func checkNotAppraised(ctx contractapi.TransactionContextInterface, id string) error {
+	valuations, _, err := readValuations(ctx, id)
+	if err != nil {
+		return err
+	}
+	if len(valuations) > 0 {
+		return fmt.Errorf("asset %s is valued by appraisers; submit a valuation instead", id)
+	}
+	return nil
+}
+
+func readValuations(ctx contractapi.TransactionContextInterface, id string) ([]*Valuation, []string, error) {
+	iter, err := ctx.GetStub().GetStateByPartialCompositeKey(valuationObjectType, []string{id})
+	if err != nil {
+		return nil, nil, fmt.Errorf("valuation query: %w", err)
+	}
+	defer iter.Close()
+
+	valuations := []*Valuation{}
+	var keys []string
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return nil, nil, fmt.Errorf("iter next: %w", err)
+		}
+		var v Valuation
+		if err := json.Unmarshal(kv.Value, &v); err != nil {
+			return nil, nil, fmt.Errorf("unmarshal valuation: %w", err)
+		}
+		valuations = append(valuations, &v)
+		keys = append(keys, kv.Key)
+	}
+	return valuations, keys, nil
+}
+
+func putValuation(ctx contractapi.TransactionContextInterface, key string, v *Valuation) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("marshal valuation: %w", err)
+	}
+	if err := ctx.GetStub().PutState(key, b); err != nil {
+		return fmt.Errorf("put state: %w", err)
+	}
+	return nil
+}
+
+// parseEffectiveDate accepts an RFC 3339 timestamp or a date, which stands
+// for midnight UTC.
+func parseEffectiveDate(raw string) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return time.Time{}, errors.New("effectiveDate is required")
+	}
+	if t, err := time.Parse(time.RFC3339Nano, raw); err == nil {
+		return t.UTC(), nil
+	}
+	t, err := time.Parse(effectiveDateLayout, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("effectiveDate %q is neither an RFC 3339 timestamp nor a YYYY-MM-DD date", raw)
+	}
+	return t, nil
+}
+
+// holdsAsset reports whether identity owns the asset or a share of it.
+func holdsAsset(asset *Asset, identity string) bool {
+	if asset.Owner == identity {
+		return true
+	}
+	for _, s := range asset.Shares {
+		if s.Holder == identity {
+			return true
+		}
+	}
+	return false
+}
+
+// requireAppraiser rejects invokers outside the configured appraiser MSPs and
+// invokers without the appraiser=true certificate attribute. Any org's CA can
+// issue the attribute, so it only counts within the appraiser MSPs.
+func (c *AssetContract) requireAppraiser(ctx contractapi.TransactionContextInterface) error {
+	appraisers := c.AppraiserMSPs
+	if len(appraisers) == 0 {
+		appraisers = []string{defaultRegulatorMSP}
+	}
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("get msp id: %w", err)
+	}
+	if !contains(appraisers, mspID) {
+		return fmt.Errorf("only %s identities may submit valuations", strings.Join(appraisers, ", "))
+	}
+	if err := ctx.GetClientIdentity().AssertAttributeValue(appraiserAttribute, "true"); err != nil {
+		return fmt.Errorf("only identities with the %s=true attribute may submit valuations", appraiserAttribute)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"testing"
+)
+
+func newAppraiser(mspID string, cn string) *mockIdentity {
+	m := newMockIdentity(mspID, cn, "client")
+	m.attrs[appraiserAttribute] = "true"
+	return m
+}
+
+func TestValuationSeries(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("house", alice.id(), "250000 EUR")
+	e.assets.AppraiserMSPs = []string{"Org2MSP"}
+	appraiser := newAppraiser("Org2MSP", "appraiser")
+
+	wantErr(t, submitValuation(e, bob, "house", "260000 EUR", "2023-12-01"), "only identities with the appraiser=true attribute may submit valuations")
+	// The attribute only counts within the appraiser MSPs.
+	wantErr(t, submitValuation(e, newAppraiser("Org1MSP", "alice"), "house", "260000 EUR", "2023-12-01"), "only Org2MSP identities may submit valuations")
+	e.assets.AppraiserMSPs = []string{"Org1MSP", "Org2MSP"}
+	wantErr(t, submitValuation(e, newAppraiser("Org1MSP", "alice"), "house", "260000 EUR", "2023-12-01"), "appraisers may not value asset house, which they hold")
+	wantErr(t, submitValuation(e, appraiser, "house", "260000 EUR", "2030-01-01"), "effectiveDate 2030-01-01T00:00:00Z is in the future")
+	wantErr(t, submitValuation(e, appraiser, "house", "260000 EUR", "last week"), "neither an RFC 3339 timestamp nor a YYYY-MM-DD date")
+	_, err := e.assets.SubmitValuation(e.tx(appraiser), "house", "260000 EUR", " ", "2023-12-01")
+	wantErr(t, err, "method is required")
+
+	ctx := e.tx(appraiser)
+	december, err := e.assets.SubmitValuation(ctx, "house", "260000 EUR", "comparable sales", "2023-12-01")
+	mustOK(t, err)
+	var submitted Valuation
+	if name := eventOf(t, ctx, &submitted); name != "ValuationSubmitted" || submitted.ID != december || submitted.Status != ValuationPending {
+		t.Fatalf("event %s %+v", name, submitted)
+	}
+	november, err := e.assets.SubmitValuation(e.tx(appraiser), "house", "255000 EUR", "desktop", "2023-11-15T12:00:00+01:00")
+	mustOK(t, err)
+	rejected, err := e.assets.SubmitValuation(e.tx(appraiser), "house", "1 EUR", "guess", "2023-12-20")
+	mustOK(t, err)
+
+	// Pending valuations do not change the value.
+	if v := e.readAsset("house").Value.String(); v != "250000.00 EUR" {
+		t.Fatalf("value = %s, want the created value", v)
+	}
+
+	wantErr(t, e.assets.ReviewValuation(e.tx(bob), "house", december, true, ""), "only the owner of asset house or an operator with the updateValue right")
+	wantErr(t, e.assets.ReviewValuation(e.tx(alice), "house", "nope", true, ""), "valuation nope of asset house does not exist")
+	mustOK(t, e.assets.ReviewValuation(e.tx(alice), "house", rejected, false, "no inspection"))
+	ctx = e.tx(alice)
+	mustOK(t, e.assets.ReviewValuation(ctx, "house", december, true, ""))
+	var reviewed Valuation
+	if name := eventOf(t, ctx, &reviewed); name != "ValuationReviewed" || reviewed.Status != ValuationAccepted || reviewed.ReviewedBy != alice.id() {
+		t.Fatalf("event %s %+v", name, reviewed)
+	}
+	wantErr(t, e.assets.ReviewValuation(e.tx(alice), "house", december, false, ""), "is already accepted")
+	asset := e.readAsset("house")
+	if asset.Value.String() != "260000.00 EUR" || asset.ValuationID != december {
+		t.Fatalf("asset %+v, want the december valuation", asset)
+	}
+
+	// An older valuation accepted later does not replace the current value.
+	mustOK(t, e.assets.ReviewValuation(e.tx(alice), "house", november, true, ""))
+	if asset := e.readAsset("house"); asset.Value.String() != "260000.00 EUR" || asset.ValuationID != december {
+		t.Fatalf("asset %+v, want the december valuation", asset)
+	}
+
+	valuations, err := e.assets.GetValuations(e.tx(carol), "house")
+	mustOK(t, err)
+	want := []struct{ id, status string }{{november, ValuationAccepted}, {december, ValuationAccepted}, {rejected, ValuationRejected}}
+	if len(valuations) != len(want) {
+		t.Fatalf("got %d valuations, want %d", len(valuations), len(want))
+	}
+	for i, w := range want {
+		if v := valuations[i]; v.ID != w.id || v.Status != w.status || v.Appraiser != appraiser.id() || v.AppraiserMSP != "Org2MSP" {
+			t.Fatalf("valuation %d = %+v, want %s %s", i, v, w.id, w.status)
+		}
+	}
+	if got := valuations[0].EffectiveAt; got != "2023-11-15T11:00:00.000000000Z" {
+		t.Fatalf("effectiveAt = %s", got)
+	}
+	if valuations[2].Note != "no inspection" {
+		t.Fatalf("note = %q", valuations[2].Note)
+	}
+
+	// Once valued, the value only changes through valuations.
+	wantErr(t, e.assets.UpdateAssetValue(e.tx(alice), "house", "270000 EUR"), "asset house is valued by appraisers; submit a valuation instead")
+	opening := `{"value":"270000 EUR","salt":"` + salt + `"}`
+	wantErr(t, e.assets.CommitAssetValue(e.tx(alice).withTransient(openingTransientKey, opening), "house"), "asset house is valued by appraisers")
+	// The valuations outlive the asset and apply to a new one under its ID.
+	mustOK(t, e.assets.DeleteAsset(e.tx(alice), "house"))
+	wantErr(t, e.assets.CreateAssetCommitted(e.tx(alice).withTransient(openingTransientKey, opening), "house", alice.id()), "asset house is valued by appraisers")
+}
+
+func TestValuationReviewedByOthers(t *testing.T) {
+	e := newTestEnv(t)
+	e.createAsset("painting", "Gallery", "1000 EUR")
+	appraiser := newAppraiser(defaultRegulatorMSP, "appraiser")
+
+	id, err := e.assets.SubmitValuation(e.tx(appraiser), "painting", "1500 EUR", "auction records", "2023-06-30")
+	mustOK(t, err)
+	// Label owners are open to any client, but never to the appraiser.
+	wantErr(t, e.assets.ReviewValuation(e.tx(appraiser), "painting", id, true, ""), "cannot be reviewed by its appraiser")
+	mustOK(t, e.assets.ReviewValuation(e.tx(carol), "painting", id, true, ""))
+	if v := e.readAsset("painting").Value.String(); v != "1500.00 EUR" {
+		t.Fatalf("value = %s", v)
+	}
+
+	_, err = e.assets.GetValuations(e.tx(carol), "\x00painting")
+	wantErr(t, err, "starts with the reserved prefix")
+	valuations, err := e.assets.GetValuations(e.tx(carol), "unknown")
+	mustOK(t, err)
+	if len(valuations) != 0 {
+		t.Fatalf("got %d valuations, want none", len(valuations))
+	}
+}
+
+func submitValuation(e *testEnv, identity *mockIdentity, id string, value string, effectiveDate string) error {
+	_, err := e.assets.SubmitValuation(e.tx(identity), id, value, "comparable sales", effectiveDate)
+	return err
+}
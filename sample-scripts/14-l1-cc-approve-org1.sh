@@ -18,4 +18,5 @@ peer lifecycle chaincode approveformyorg \
   --package-id "${PKG_ID}" \
   --sequence "${CC_SEQUENCE}" \
   --signature-policy "${CC_ENDORSEMENT_POLICY}" \
+  --collections-config "${CC_COLLECTIONS_CONFIG}" \
   --tls --cafile "${ORDERER_CA}"
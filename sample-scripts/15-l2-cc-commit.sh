@@ -14,6 +14,7 @@ peer lifecycle chaincode checkcommitreadiness \
   --version "${CC_VERSION}" \
   --sequence "${CC_SEQUENCE}" \
   --signature-policy "${CC_ENDORSEMENT_POLICY}" \
+  --collections-config "${CC_COLLECTIONS_CONFIG}" \
   --output json
 
 echo "==> Commit chaincode definition"
@@ -24,6 +25,7 @@ peer lifecycle chaincode commit \
   --version "${CC_VERSION}" \
   --sequence "${CC_SEQUENCE}" \
   --signature-policy "${CC_ENDORSEMENT_POLICY}" \
+  --collections-config "${CC_COLLECTIONS_CONFIG}" \
   --peerAddresses "peer0.org1.${DOMAIN}:${ORG1_PEER_PORT}" \
   --tlsRootCertFiles "${ROOT_DIR}/organizations/peerOrganizations/org1.${DOMAIN}/peers/peer0.org1.${DOMAIN}/tls/ca.crt" \
   --peerAddresses "peer0.org2.${DOMAIN}:${ORG2_PEER_PORT}" \